@@ -1,8 +1,14 @@
 module github.com/kivilahtio/go-re
 
-go 1.15
+go 1.23
 
 require (
 	github.com/google/licensecheck v0.3.1 // test
 	github.com/smartystreets/goconvey v1.6.4 // test
 )
+
+require (
+	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
+	github.com/jtolds/gls v4.20.0+incompatible // indirect
+	github.com/smartystreets/assertions v0.0.0-20180927180507-b2de0cb4f26d // indirect
+)
@@ -0,0 +1,170 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+/*
+noMatch is a character class without characters, the \G of the alternatives anchored at the position is rewritten to it for searching past the position
+*/
+const noMatch = `[^\x00-\x{10FFFF}]`
+
+/*
+anchor is the emulation of \G, which the Go engine does not have.
+\G is only supported at the start of a top-level alternative, so an alternative either matches right at the position or anywhere like without \G.
+The matching pattern keeps the alternatives without \G to search past the position, the anchored variants match all of them at the position.
+*/
+type anchor struct {
+	floating bool   // some top-level alternative does not start with \G, and can match after the position
+	body     string // the matching pattern as written, with \G, see RE.body
+	stripped string // the matching pattern without \G
+
+	once  sync.Once
+	start *regexp.Regexp // stripped anchored at the start of the haystack
+	at    *regexp.Regexp // stripped anchored after the first character, to keep the character before the position visible to the assertions
+}
+
+/*
+anchorParser finds \G in the matching pattern, and rewrites the alternatives starting with it to never match, see anchor.
+Flag groups like (?i) may precede \G.
+*/
+func anchorParser(r *RE) error {
+	regstr := *r.n
+	var positions []int // offsets of \G in regstr
+	floating := false
+
+	depth := 0
+	alternative, anchored := true, false // at the start of a top-level alternative, and it starts with \G
+	var inBracketedCharacterClass bool
+	for i := r.nPrefix; i < len(regstr); i++ {
+		c := regstr[i]
+		switch {
+		case inBracketedCharacterClass:
+			if c == '\\' {
+				i++
+			} else if c == ']' {
+				inBracketedCharacterClass = false
+			}
+			continue
+		case c == '[':
+			inBracketedCharacterClass = true
+		case c == '\\' && i+1 < len(regstr):
+			if regstr[i+1] == 'G' {
+				if depth > 0 || !alternative {
+					return newParseError(r, ErrUnsupportedFeature, ComponentMatch, r.nPos[i], "\\G is only supported at the start of a top-level alternative")
+				}
+				positions = append(positions, i)
+				anchored = true
+				i++
+				continue
+			}
+			i++
+		case c == '(' && depth == 0 && alternative && flagGroup(regstr, i) > 0:
+			i = flagGroup(regstr, i) - 1
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			floating = floating || !anchored
+			alternative, anchored = true, false
+			continue
+		}
+		alternative = false
+	}
+	if positions == nil {
+		return nil
+	}
+	floating = floating || !anchored
+
+	a := &anchor{floating: floating, body: regstr[r.nPrefix:]}
+	stripped, blocked := strings.Builder{}, strings.Builder{}
+	newPos := make([]int, 0, len(r.nPos)+len(positions)*len(noMatch))
+	last := 0
+	for _, p := range positions {
+		stripped.WriteString(regstr[last:p])
+		blocked.WriteString(regstr[last:p])
+		newPos = append(newPos, r.nPos[last:p]...)
+		blocked.WriteString(noMatch)
+		for j := 0; j < len(noMatch); j++ {
+			newPos = append(newPos, r.nPos[p])
+		}
+		last = p + 2
+	}
+	stripped.WriteString(regstr[last:])
+	blocked.WriteString(regstr[last:])
+	newPos = append(newPos, r.nPos[last:]...)
+
+	a.stripped = stripped.String()
+	*r.n = blocked.String()
+	r.nPos = newPos
+	r.anchor = a
+	return nil
+}
+
+/*
+flagGroup returns the offset after the flag group like (?i) or (?-s) opening at offset i, 0 if it is something else
+*/
+func flagGroup(regstr string, i int) int {
+	if !strings.HasPrefix(regstr[i:], "(?") {
+		return 0
+	}
+	for j := i + 2; j < len(regstr); j++ {
+		switch c := regstr[j]; {
+		case c == ')':
+			if j == i+2 {
+				return 0
+			}
+			return j + 1
+		case c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+/*
+searchAnchored finds the first match starting at or after the offset, with the alternatives starting with \G only matching at the anchor.
+*/
+func (r *RE) searchAnchored(haystack string, from, anchor int) []int {
+	var match []int
+	if r.anchor.floating {
+		match = r.search(haystack, from)
+	}
+	if from <= anchor && anchor <= len(haystack) && (match == nil || match[0] >= anchor) {
+		// At the anchor every alternative is tried in order, like Perl does
+		if at := r.anchor.match(haystack, anchor); at != nil {
+			return at
+		}
+	}
+	return match
+}
+
+/*
+match matches the pattern without \G exactly at the offset
+*/
+func (a *anchor) match(haystack string, offset int) []int {
+	a.once.Do(func() {
+		a.start = regexp.MustCompile(`\A(` + a.stripped + `)`)
+		a.at = regexp.MustCompile(`\A(?s:.)(` + a.stripped + `)`)
+	})
+	if offset == 0 {
+		return shiftMatch(a.start.FindStringSubmatchIndex(haystack), 0)
+	}
+	_, size := utf8.DecodeLastRuneInString(haystack[:offset])
+	return shiftMatch(a.at.FindStringSubmatchIndex(haystack[offset-size:]), offset-size)
+}
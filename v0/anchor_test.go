@@ -0,0 +1,104 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleMatcher_lexer() {
+	src := "x = 42 + y"
+	mt := NewMatcher(src)
+	for mt.Pos() < len(src) {
+		switch {
+		case mt.M(`m/\G\s+/gc`):
+		case mt.M(`m/\G(\d+)/gc`):
+			fmt.Println("number", mt.Last.S[1])
+		case mt.M(`m/\G(\w+)/gc`):
+			fmt.Println("name", mt.Last.S[1])
+		case mt.M(`m/\G([=+])/gc`):
+			fmt.Println("operator", mt.Last.S[1])
+		default:
+			fmt.Println("unexpected", src[mt.Pos():])
+			return
+		}
+	}
+	// Output:
+	// name x
+	// operator =
+	// number 42
+	// operator +
+	// name y
+}
+
+func TestAnchor(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("matches under /g are adjacent", t, func() {
+		r := Mr("1 2 3x4", `m/\G\s*(\d)/g`)
+		So(r.Matches, ShouldEqual, 3)
+		So(r.S, ShouldResemble, []string{"", "1", "2", "3"})
+		So(Mr("x1 2", `m/\G\s*(\d)/g`).Matches, ShouldEqual, 0)
+		So(M("xa", `m/\Ga/`), ShouldBeFalse)
+		So(M("ax", `m/\Ga/`), ShouldBeTrue)
+		So(Ss("  12 34 x 56", `s/\G\s*(\d+)/<$1>/g`), ShouldEqual, "<12><34> x 56")
+	})
+	Convey("alternatives without \\G match anywhere", t, func() {
+		r := Mr("aab ab", `m/\Ga|b/g`)
+		So(r.Matches, ShouldEqual, 4)
+		So(r.All()[3].Span, ShouldResemble, [2]int{5, 6})
+		So(Mr("ba", `m/b|\G(a)/g`).S, ShouldResemble, []string{"", "", "a"})
+		So(Mr("ab", `m/\Ga|\Gab/`).Match(), ShouldEqual, "a")
+	})
+	Convey("flags and the x flag", t, func() {
+		So(Mr("AaB", `m/\Ga/gi`).Matches, ShouldEqual, 2)
+		So(Mr("AaB", `m/(?i)\Ga/g`).Matches, ShouldEqual, 2)
+		So(Mr("1 2", `m/ \G \s* \d   # a digit
+			/gx`).Matches, ShouldEqual, 2)
+		So(MustQr(`m/\G\d+/g`).String(), ShouldEqual, `(?^:\G\d+)`)
+	})
+	Convey("the position of a Matcher anchors \\G", t, func() {
+		mt := NewMatcher("ab cd")
+		mt.SetPos(3)
+		So(mt.M(`m/\G\bcd/g`), ShouldBeTrue)
+		So(mt.Pos(), ShouldEqual, 5)
+		mt.SetPos(4)
+		So(mt.M(`m/\G\bd/g`), ShouldBeFalse)
+		So(mt.Pos(), ShouldEqual, 0)
+
+		mt = NewMatcher("aab")
+		mt.SetPos(2)
+		So(mt.M(`m/\Gb/`), ShouldBeTrue)
+		So(mt.M(`m/\Ga/`), ShouldBeFalse)
+		So(mt.Pos(), ShouldEqual, 2)
+	})
+	Convey("empty matches", t, func() {
+		So(Mr("a", `m/\G\s*/g`).Matches, ShouldEqual, 1)
+		mt := NewMatcher("ab")
+		So(mt.M(`m/\Gx*/g`), ShouldBeTrue)
+		So(mt.Next(), ShouldBeFalse)
+	})
+	Convey("\\G elsewhere is not supported", t, func() {
+		runErrorTest(`m/a\G/`, ErrUnsupportedFeature)
+		runErrorTest(`m/(\Ga)/`, ErrUnsupportedFeature)
+		runErrorTest(`m/a|b\G/`, ErrUnsupportedFeature)
+		runErrorTest(`m/\G(a)\1/`, ErrUnsupportedFeature)
+		runErrorPositionTest(`m/a\G/`, ComponentMatch, 3)
+		runErrorPositionTest(`m/ a \G /x`, ComponentMatch, 5)
+		So(M(`a\G`, `m/[\\G]/`), ShouldBeTrue)
+		So(M(`\G`, `m/\\G/`), ShouldBeTrue)
+
+		_, err := QrWith(`m/x$p/`, map[string]*Pattern{"p": MustQr(`m/\Ga/`)})
+		So(errors.Is(err, ErrUnsupportedFeature), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,168 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import "strings"
+
+/*
+namedGroupParser normalises the Perl spellings of named capture groups (?<name>...) and (?'name'...) to the Go (?P<name>...).
+The Go engine has no backreferences nor lookbehinds, so \1, \g1, \g{-1}, \g{name}, \k<name>, \k'name', \k{name}, (?P=name),
+(?<=...) and (?<!...) are rejected with ErrUnsupportedFeature.
+*/
+func namedGroupParser(r *RE) error {
+	regstr := *r.n
+	sb := strings.Builder{}
+	sb.Grow(len(regstr))
+	newPos := make([]int, 0, len(r.nPos))
+
+	var inBracketedCharacterClass bool
+	for i := 0; i < len(regstr); i++ {
+		switch c := regstr[i]; {
+		case c == '[':
+			inBracketedCharacterClass = true
+		case c == ']':
+			inBracketedCharacterClass = false
+		case c == '\\' && i+1 < len(regstr):
+			if !inBracketedCharacterClass {
+				end, err := backrefEscape(r, regstr, i)
+				if err != nil {
+					return err
+				}
+				if end > 0 {
+					return newParseError(r, ErrUnsupportedFeature, ComponentMatch, r.nPos[i], "backreference '%s' is not supported by the Go regexp engine", regstr[i:end])
+				}
+			}
+			sb.WriteString(regstr[i : i+2])
+			newPos = append(newPos, r.nPos[i], r.nPos[i])
+			i++ // Skip the escaping backslash and the character being escaped
+			continue
+		case c == '(' && !inBracketedCharacterClass && (strings.HasPrefix(regstr[i:], "(?<=") || strings.HasPrefix(regstr[i:], "(?<!")):
+			return newParseError(r, ErrUnsupportedFeature, ComponentMatch, r.nPos[i], "lookbehind '%s' is not supported by the Go regexp engine", regstr[i:i+4])
+		case c == '(' && !inBracketedCharacterClass && strings.HasPrefix(regstr[i:], "(?"):
+			name, end, closing := groupName(regstr, i)
+			if end < 0 {
+				return newParseError(r, ErrSyntax, ComponentMatch, r.nPos[i], "malformed group name")
+			}
+			if name == "" {
+				break
+			}
+			if closing == ')' {
+				return newParseError(r, ErrUnsupportedFeature, ComponentMatch, r.nPos[i], "backreference '%s' is not supported by the Go regexp engine", regstr[i:end])
+			}
+			group := `(?P<` + name + `>`
+			sb.WriteString(group)
+			for j := 0; j < len(group); j++ {
+				newPos = append(newPos, r.nPos[i])
+			}
+			i = end - 1
+			continue
+		}
+		sb.WriteByte(regstr[i])
+		newPos = append(newPos, r.nPos[i])
+	}
+	*r.n = sb.String()
+	r.nPos = newPos
+	return nil
+}
+
+/*
+groupName parses the name of the group opening at offset i, (?<name>, (?'name', (?P<name> or (?P=name).
+end is the offset after the name and its closing character, or -1 if the name is malformed. The name is "" if the group is not named.
+*/
+func groupName(regstr string, i int) (name string, end int, closing byte) {
+	j := i + 2
+	if j < len(regstr) && regstr[j] == 'P' {
+		j++
+	}
+	if j >= len(regstr) {
+		return "", i, 0
+	}
+	switch regstr[j] {
+	case '<':
+		closing = '>'
+		if j+1 < len(regstr) && (regstr[j+1] == '=' || regstr[j+1] == '!') {
+			return "", i, 0 // lookbehind
+		}
+	case '\'':
+		closing = '\''
+	case '=':
+		if regstr[j-1] != 'P' {
+			return "", i, 0 // lookahead
+		}
+		closing = ')'
+	default:
+		return "", i, 0
+	}
+	name, end = variableName(regstr, j) // variableName skips the character before the name
+	if name == "" || end >= len(regstr) || regstr[end] != closing {
+		return "", -1, 0
+	}
+	return name, end + 1, closing
+}
+
+/*
+backrefEscape parses the escape sequence at offset i as a backreference, returns the offset after it, or 0 if it is something else.
+*/
+func backrefEscape(r *RE, regstr string, i int) (int, error) {
+	j := i + 1
+	switch c := regstr[j]; {
+	case '1' <= c && c <= '9':
+		end := j
+		for end < len(regstr) && '0' <= regstr[end] && regstr[end] <= '9' {
+			end++
+		}
+		return end, nil
+	case c == 'k':
+		if j+1 < len(regstr) {
+			var closing byte
+			switch regstr[j+1] {
+			case '<':
+				closing = '>'
+			case '\'':
+				closing = '\''
+			case '{':
+				closing = '}'
+			}
+			if closing != 0 {
+				name, end := variableName(regstr, j+1)
+				if name != "" && end < len(regstr) && regstr[end] == closing {
+					return end + 1, nil
+				}
+			}
+		}
+		return 0, newParseError(r, ErrSyntax, ComponentMatch, r.nPos[i], "malformed \\k, expected \\k<name>, \\k'name' or \\k{name}")
+	case c == 'g':
+		j++
+		braced := j < len(regstr) && regstr[j] == '{'
+		if braced {
+			j++
+		}
+		end := j
+		if end < len(regstr) && regstr[end] == '-' {
+			end++
+		}
+		digits := end
+		for end < len(regstr) && '0' <= regstr[end] && regstr[end] <= '9' {
+			end++
+		}
+		name := ""
+		if end == digits && braced && end == j {
+			name, end = variableName(regstr, j-1)
+		}
+		if (strings.Trim(regstr[digits:end], "0") == "" && name == "") || (braced && (end >= len(regstr) || regstr[end] != '}')) {
+			return 0, newParseError(r, ErrSyntax, ComponentMatch, r.nPos[i], "malformed \\g, expected \\gN, \\g{N}, \\g{-N} or \\g{name}")
+		}
+		if braced {
+			end++
+		}
+		return end, nil
+	}
+	return 0, nil
+}
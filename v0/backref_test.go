@@ -0,0 +1,64 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNamedGroups(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("every spelling of a named group populates Z", t, func() {
+		z := map[string]string{"year": "2021"}
+		runMatchTest("2021-03-04", `m/(?P<year>\d{4})/`, 1, []string{"", "2021"}, z, "Go")
+		runMatchTest("2021-03-04", `m/(?<year>\d{4})/`, 1, []string{"", "2021"}, z, "Perl")
+		runMatchTest("2021-03-04", `m/(?'year'\d{4})/`, 1, []string{"", "2021"}, z, "Perl, quoted")
+		runMatchTest("2021-03-04", `m{ (?<year> \d{4} ) }x`, 1, []string{"", "2021"}, z, "Perl, extended")
+	})
+	Convey("lookbehinds are not named groups", t, func() {
+		_, err := Compile(`m/(?<=k)alle/`)
+		So(errors.Is(err, ErrUnsupportedFeature), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "lookbehind '(?<=' is not supported")
+		_, err = Compile(`m/(?<!k)alle/`)
+		So(errors.Is(err, ErrUnsupportedFeature), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "lookbehind '(?<!' is not supported")
+		runErrorPositionTest(`m/k(?<!k)alle/`, ComponentMatch, 3)
+		So(M("(?<=", `m/[(?<=]+/`), ShouldBeTrue)
+	})
+	Convey("malformed names", t, func() {
+		runErrorTest(`m/(?<1st>a)/`, ErrSyntax)
+		runErrorTest(`m/(?'first>a)/`, ErrSyntax)
+	})
+}
+
+func TestBackreferences(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("the Go engine has no backreferences", t, func() {
+		for _, needle := range []string{
+			`m/(\w+) \1/`, `m/(\w+) \g1/`, `m/(\w+) \g{1}/`, `m/(\w+) \g{-1}/`, `m/(\w+) \g-1/`,
+			`m/(?<w>\w+) \k<w>/`, `m/(?<w>\w+) \k'w'/`, `m/(?<w>\w+) \k{w}/`, `m/(?<w>\w+) \g{w}/`, `m/(?<w>\w+) (?P=w)/`,
+		} {
+			runErrorTest(needle, ErrUnsupportedFeature)
+		}
+		runErrorPositionTest(`m/(a) \2/`, ComponentMatch, 6)
+		runErrorPositionTest(`m/(?<a>a) (?P=a)/`, ComponentMatch, 10)
+	})
+	Convey("malformed backreferences", t, func() {
+		runErrorTest(`m/(a)\k/`, ErrSyntax)
+		runErrorTest(`m/(a)\g{}/`, ErrSyntax)
+		runErrorTest(`m/(a)\g0/`, ErrSyntax)
+	})
+	Convey("the replacement may still refer to the groups", t, func() {
+		runSubstTest("kalle ankka", `s/(\w+) (\w+)/\2 \1/`, "ankka kalle", 1, []string{"", "kalle", "ankka"}, nilNameCap)
+	})
+}
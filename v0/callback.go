@@ -0,0 +1,94 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+/*
+Se substitutes each match with what the callback returns, like Perl's s/.../code/e.
+
+	str := "3 apples and 5 oranges"
+	Se(&str, `s/(\d+)//eg`, func(m *RE) string {
+		n, _ := strconv.Atoi(m.S[1])
+		return strconv.Itoa(n * 2)
+	})
+
+The callback gets a new RE for each match, with S and Z holding the captures of that match only, Index the number of the match and Offsets the offsets of the groups.
+The e flag may be given in the needle, the callback is used instead of the replacement expression, which is not even parsed.
+The RE returned holds the captures of all the matches, just like Sr.
+*/
+func Se(haystack *string, needle string, fn func(m *RE) string) *RE {
+	return defaultSession.Se(haystack, needle, fn)
+}
+
+/*
+SeE is Se, but returns an error instead of panicking on an invalid needle.
+*/
+func SeE(haystack *string, needle string, fn func(m *RE) string) (*RE, error) {
+	return defaultSession.SeE(haystack, needle, fn)
+}
+
+func (self *Session) Se(haystack *string, needle string, fn func(m *RE) string) *RE {
+	r, err := self.SeE(haystack, needle, fn)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			self.diagnose("%s", perr.Pretty())
+		}
+		panic(err)
+	}
+	return r
+}
+
+func (self *Session) SeE(haystack *string, needle string, fn func(m *RE) string) (*RE, error) {
+	parser := self.callbackParserE
+	if fn == nil {
+		parser = self.regexParserE // the replacement is used after all
+	}
+	r, err := parser(&needle)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode != 's' {
+		return nil, newParseError(r, ErrSyntax, ComponentMode, 0, "not a s/// needle")
+	}
+	self.setLast(r)
+	return se(haystack, r, fn)
+}
+
+/*
+SubstFunc returns the haystack with each match substituted by what the callback returns, honouring the flags of the needle, just like Se.
+*/
+func (p *Pattern) SubstFunc(haystack string, fn func(m *RE) string) (string, *RE) {
+	r := *p.re
+	se(&haystack, &r, fn)
+	return haystack, &r
+}
+
+/*
+SubstAllFunc is SubstFunc with the g flag.
+*/
+func (p *Pattern) SubstAllFunc(haystack string, fn func(m *RE) string) (string, *RE) {
+	r := *p.all
+	se(&haystack, &r, fn)
+	return haystack, &r
+}
+
+/*
+matchRE is the RE given to the callback for the i:th match.
+*/
+func matchRE(r *RE, haystack string, match []int, i int) *RE {
+	m := *r
+	m.Matches, m.S, m.Z, m.Result = 1, nil, nil, ""
+	m.Index = i
+	m.Offsets = append([]int(nil), match...)
+	matchResults(&m, haystack, [][]int{match})
+	if m.captures {
+		captureGroups(&m, haystack, [][]int{match})
+	}
+	return &m
+}
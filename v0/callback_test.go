@@ -0,0 +1,105 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleSe() {
+	str := "3 apples and 5 oranges"
+	Se(&str, `s/(\d+)//eg`, func(m *RE) string {
+		n, _ := strconv.Atoi(m.S[1])
+		return strconv.Itoa(n * 2)
+	})
+	fmt.Println(str)
+	// Output: 6 apples and 10 oranges
+}
+
+func TestSe(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("each match gets its own RE", t, func() {
+		str := "2021-03-04 and 2022-12-31"
+		var seen []*RE
+		r := Se(&str, `s/(?<y>\d{4})-(\d\d)-(\d\d)/'dates'/eg`, func(m *RE) string {
+			seen = append(seen, m)
+			return m.S[3] + "." + m.S[2] + "." + m.Z["y"]
+		})
+		So(str, ShouldEqual, "04.03.2021 and 31.12.2022")
+		So(r.Matches, ShouldEqual, 2)
+		So(r.S, ShouldResemble, []string{"", "2021", "03", "04", "2022", "12", "31"})
+		So(R0, ShouldEqual, r)
+
+		So(len(seen), ShouldEqual, 2)
+		So(seen[0].Index, ShouldEqual, 0)
+		So(seen[0].Matches, ShouldEqual, 1)
+		So(seen[0].S, ShouldResemble, []string{"", "2021", "03", "04"})
+		So(seen[0].Z, ShouldResemble, map[string]string{"y": "2021"})
+		So(seen[0].Offsets, ShouldResemble, []int{0, 10, 0, 4, 5, 7, 8, 10})
+		So(seen[1].Index, ShouldEqual, 1)
+		So(seen[1].S, ShouldResemble, []string{"", "2022", "12", "31"})
+		So(seen[1].Offsets[:2], ShouldResemble, []int{15, 25})
+	})
+	Convey("without g only the first match", t, func() {
+		str := "a b c"
+		calls := 0
+		Se(&str, `s/\w//e`, func(m *RE) string {
+			calls++
+			return strings.ToUpper(str[m.Offsets[0]:m.Offsets[1]])
+		})
+		So(str, ShouldEqual, "A b c")
+		So(calls, ShouldEqual, 1)
+	})
+	Convey("groups which did not match", t, func() {
+		str := "a"
+		Se(&str, `s/(a)|(b)//e`, func(m *RE) string {
+			So(m.Offsets, ShouldResemble, []int{0, 1, 0, 1, -1, -1})
+			return "x"
+		})
+		So(str, ShouldEqual, "x")
+	})
+	Convey("Patterns", t, func() {
+		p := MustQr(`s/(\d+)//e`)
+		count := func(m *RE) string { return fmt.Sprintf("<%d:%s>", m.Index, m.S[1]) }
+		str, r := p.SubstFunc("1 2 3", count)
+		So(str, ShouldEqual, "<0:1> 2 3")
+		So(r.Matches, ShouldEqual, 1)
+		str, r = p.SubstAllFunc("1 2 3", count)
+		So(str, ShouldEqual, "<0:1> <1:2> <2:3>")
+		So(r.Matches, ShouldEqual, 3)
+	})
+	Convey("the callback overrides the replacement expression", t, func() {
+		str := "kalle"
+		Se(&str, `s/k/uc($&)/e`, func(m *RE) string { return "C" })
+		So(str, ShouldEqual, "Calle")
+	})
+	Convey("the replacement is not parsed when the callback takes its place", t, func() {
+		str := "call 1 and 2"
+		Se(&str, `s/(\d+)/call me/eg`, func(m *RE) string { return "<" + m.S[1] + ">" })
+		So(str, ShouldEqual, "call <1> and <2>")
+		_, err := SeE(&str, `s/(\d+)/${/g`, func(m *RE) string { return "" })
+		So(err, ShouldBeNil)
+		So(str, ShouldEqual, "call <> and <>")
+		_, err = SE(&str, `s/(\d+)/call me/eg`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+	})
+	Convey("only s/// takes a callback", t, func() {
+		str := "kalle"
+		_, err := SeE(&str, `m/k/`, func(m *RE) string { return "" })
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		runErrorPositionTest(`m/k/ge`, ComponentFlags, 5)
+	})
+}
@@ -0,0 +1,86 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"regexp/syntax"
+	"sort"
+)
+
+/*
+CaptureGroup describes a capture group of the matching pattern, as found in the parsed regexp.
+*/
+type CaptureGroup struct {
+	Index  int    // $n, the number of the group
+	Name   string // the name of a named group, "" otherwise
+	Parent int    // the number of the enclosing capture group, 0 for top-level groups
+	Depth  int    // how many capture groups enclose this one
+}
+
+/*
+NumCaptures returns the number of capture groups in the matching pattern.
+*/
+func (r *RE) NumCaptures() int {
+	return len(r.groups)
+}
+
+/*
+CaptureNames returns the names of the capture groups, indexed like S. Index 0 and unnamed groups are "".
+*/
+func (r *RE) CaptureNames() []string {
+	names := make([]string, len(r.groups)+1)
+	for _, group := range r.groups {
+		names[group.Index] = group.Name
+	}
+	return names
+}
+
+/*
+CaptureGroups returns the capture groups of the matching pattern in the order of their opening parenthesis, with their nesting.
+*/
+func (r *RE) CaptureGroups() []CaptureGroup {
+	return append([]CaptureGroup(nil), r.groups...)
+}
+
+/*
+captureAnalyser finds the capture groups from the parsed syntax tree of the preprocessed matching pattern, so (?i), (?s:...), [(], \Q(\E and friends are not mistaken for groups.
+*/
+func captureAnalyser(r *RE) error {
+	tree, err := syntax.Parse(*r.n, syntax.Perl)
+	if err != nil {
+		return err
+	}
+	r.groups = nil
+	captureWalker(r, tree, 0, 0)
+	sort.Slice(r.groups, func(i, j int) bool { return r.groups[i].Index < r.groups[j].Index })
+	r.captures = len(r.groups) > 0
+	r.nCaptures = false
+	for _, group := range r.groups {
+		if group.Name != "" {
+			r.nCaptures = true
+		}
+	}
+	return nil
+}
+
+func captureWalker(r *RE, node *syntax.Regexp, parent int, depth int) {
+	if node.Op == syntax.OpCapture {
+		r.groups = append(r.groups, CaptureGroup{
+			Index:  node.Cap,
+			Name:   node.Name,
+			Parent: parent,
+			Depth:  depth,
+		})
+		parent, depth = node.Cap, depth+1
+	}
+	for _, sub := range node.Sub {
+		captureWalker(r, sub, parent, depth)
+	}
+}
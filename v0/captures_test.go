@@ -0,0 +1,90 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCaptures(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("capture detection", t, func() {
+		runCaptureTest(`m/(?i)kalle/`, 0, []string{""})
+		runCaptureTest(`m/(?s:kal.le)/`, 0, []string{""})
+		runCaptureTest(`m/kal[(]le/`, 0, []string{""})
+		runCaptureTest(`m/\Q(kalle\E/`, 0, []string{""})
+		runCaptureTest(`m/kalle # (commented out)
+		/x`, 0, []string{""})
+		runCaptureTest(`m/(?<first>\w+) (?P<last>\w+)/`, 2, []string{"", "first", "last"})
+		runCaptureTest(`m/(k)(?:a)((l)(?P<e>le))/`, 4, []string{"", "", "", "", "e"})
+	})
+	Convey("capture fast-paths are correct", t, func() {
+		runMatchTest("KALLE", `m/(?i)kalle/`, 1, nilCapture, nilNameCap, "(?i) is not a capture group")
+		runMatchTest("ka(lle", `m/\Q(\E(l+)/`, 1, []string{"", "ll"}, nilNameCap, `\Q(\E is not a capture group`)
+		runMatchTest("kalle ankka", `m/(?<first>\w+) (\w+)/`, 1, []string{"", "kalle", "ankka"}, map[string]string{"first": "kalle"}, "Go 1.22 named groups")
+	})
+	Convey("nesting", t, func() {
+		r, _ := Compile(`m/(k)((a)(?P<l>l(l)))/`)
+		So(r.CaptureGroups(), ShouldResemble, []CaptureGroup{
+			{Index: 1, Parent: 0, Depth: 0},
+			{Index: 2, Parent: 0, Depth: 0},
+			{Index: 3, Parent: 2, Depth: 1},
+			{Index: 4, Name: "l", Parent: 2, Depth: 1},
+			{Index: 5, Parent: 4, Depth: 2},
+		})
+	})
+	Convey("every value of the named groups", t, func() {
+		r := Mr("user=kalle level=ERROR msg= user=ankka msg=ok", `m/(?:user=(?<user>\w+)|level=(?<level>\w+)|msg=(?<msg>\w*))/g`)
+		So(r.ZAll["user"], ShouldResemble, []string{"kalle", "", "", "ankka", ""})
+		So(r.ZAllMatched["user"], ShouldResemble, []bool{true, false, false, true, false})
+		So(r.ZAll["msg"], ShouldResemble, []string{"", "", "", "", "ok"})
+		So(r.ZAllMatched["msg"], ShouldResemble, []bool{false, false, true, false, true})
+		So(r.Z, ShouldResemble, map[string]string{"user": "ankka", "level": "ERROR", "msg": "ok"})
+		So(r.ZAllMatched["missing"], ShouldBeNil)
+
+		r = Mr(" !28! ", `m/(?P<noemptyoverload>!\d+!)(?P<noemptyoverload>\d+)?/`)
+		So(r.ZAll, ShouldResemble, map[string][]string{"noemptyoverload": {"!28!", ""}})
+		So(r.ZAllMatched["noemptyoverload"], ShouldResemble, []bool{true, false})
+
+		r = Mr("a1b2", `m/(?<l>[a-z])(?<d>\d)(?<l>x)?/g`)
+		So(r.ZAll, ShouldResemble, map[string][]string{"l": {"a", "", "b", ""}, "d": {"1", "2"}})
+
+		So(Mr("kalle", `m/(\w)/g`).ZAll, ShouldBeNil)
+		So(Mr("kalle", `m/(?<x>x)/g`).ZAll, ShouldBeNil)
+	})
+	Convey("substitutions and callbacks", t, func() {
+		str := "a1 b2"
+		r := Sr(&str, `s/(?<l>\w)(?<d>\d)/$2$1/g`)
+		So(str, ShouldEqual, "1a 2b")
+		So(r.ZAll, ShouldResemble, map[string][]string{"l": {"a", "b"}, "d": {"1", "2"}})
+		Se(&str, `s/(?<d>\d)//eg`, func(m *RE) string {
+			So(m.ZAll["d"], ShouldResemble, []string{m.S[1]})
+			return ""
+		})
+	})
+	Convey("patterns from Go regexps", t, func() {
+		p := FromRegexp(regexp.MustCompile(`(?i)(a)(?P<b>b)`))
+		So(p.NumCaptures(), ShouldEqual, 2)
+		So(p.CaptureNames(), ShouldResemble, []string{"", "", "b"})
+	})
+}
+
+func runCaptureTest(needle string, n int, names []string) {
+	Convey(fmt.Sprintf(`"%s" has %d capture groups`, needle, n), func() {
+		r, err := Compile(needle)
+		So(err, ShouldBeNil)
+		So(r.NumCaptures(), ShouldEqual, n)
+		So(r.CaptureNames(), ShouldResemble, names)
+	})
+}
@@ -0,0 +1,165 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const caseModifiers = "ulULFQE"
+
+/*
+caseState tracks the case modification escapes while expanding a replacement, like Perl does in double-quoted strings.
+
+\U, \L and \F replace each other, and \Q nests with them. \E ends the latest one still in effect.
+\u and \l change the next character written, after the \U, \L or \F in effect, so both \u\L$1 and \L\u$1 titlecase the name in $1.
+*/
+type caseState struct {
+	modes []byte // the \U, \L, \F and \Q in effect, the latest last
+	next  byte   // the pending \u or \l, 0 if none
+}
+
+func (cs *caseState) modify(c byte) {
+	switch c {
+	case 'u', 'l':
+		cs.next = c
+	case 'E':
+		if len(cs.modes) > 0 {
+			cs.modes = cs.modes[:len(cs.modes)-1]
+		}
+	case 'U', 'L', 'F':
+		if n := len(cs.modes); n > 0 && cs.modes[n-1] != 'Q' {
+			cs.modes = cs.modes[:n-1]
+		}
+		cs.modes = append(cs.modes, c)
+	case 'Q':
+		cs.modes = append(cs.modes, c)
+	}
+}
+
+/*
+write appends the text to dst, modified by the escapes in effect.
+*/
+func (cs *caseState) write(dst []byte, text string) []byte {
+	if text == "" {
+		return dst
+	}
+	if cs.next == 0 && len(cs.modes) == 0 {
+		return append(dst, text...)
+	}
+
+	var quote bool
+	for _, mode := range cs.modes {
+		if mode == 'Q' {
+			quote = true
+		} else {
+			text = caseMap(mode, text)
+		}
+	}
+	if cs.next != 0 {
+		c, size := utf8.DecodeRuneInString(text)
+		text = caseMap(cs.next, string(c)) + text[size:]
+		cs.next = 0
+	}
+	if quote {
+		text = quoteMeta(text)
+	}
+	return append(dst, text...)
+}
+
+/*
+caseMap changes the case of the text like Perl's uc (U), lc (L), fc (F), ucfirst (u) and lcfirst (l) of a single character.
+*/
+func caseMap(mode byte, text string) string {
+	switch mode {
+	case 'L', 'l':
+		return strings.ToLower(text)
+	}
+
+	sb := strings.Builder{}
+	sb.Grow(len(text))
+	for _, c := range text {
+		switch {
+		case c == 'ß' && mode == 'U':
+			sb.WriteString("SS")
+		case c == 'ß' && mode == 'u':
+			sb.WriteString("Ss")
+		case (c == 'ß' || c == 'ẞ') && mode == 'F':
+			sb.WriteString("ss")
+		case mode == 'U':
+			sb.WriteRune(unicode.ToUpper(c))
+		case mode == 'u':
+			sb.WriteRune(unicode.ToTitle(c))
+		default: // simple case folding, eg. 'ſ' and 'S' to 's', 'K' (Kelvin) to 'k'
+			sb.WriteRune(unicode.ToLower(unicode.ToUpper(c)))
+		}
+	}
+	return sb.String()
+}
+
+/*
+preserveCase gives the replacement the letter case of the matched text, for the k flag:
+all lowercase, all uppercase or titlecase when the matched text is so,
+character by character when it has mixed case and as many characters as the replacement, otherwise the replacement is kept as is.
+A single uppercase letter counts as titlecase.
+*/
+func preserveCase(matched string, replacement string) string {
+	var upper, lower int
+	for _, c := range matched {
+		if unicode.IsUpper(c) || unicode.IsTitle(c) {
+			upper++
+		} else if unicode.IsLower(c) {
+			lower++
+		}
+	}
+	first, _ := utf8.DecodeRuneInString(matched)
+	switch {
+	case upper == 0 && lower == 0, replacement == "":
+		return replacement
+	case upper == 0:
+		return caseMap('L', replacement)
+	case lower == 0 && upper > 1:
+		return caseMap('U', replacement)
+	case upper == 1 && (unicode.IsUpper(first) || unicode.IsTitle(first)):
+		c, size := utf8.DecodeRuneInString(replacement)
+		return caseMap('u', string(c)) + caseMap('L', replacement[size:])
+	}
+
+	m, repl := []rune(matched), []rune(replacement)
+	if len(m) != len(repl) {
+		return replacement
+	}
+	for i, c := range m {
+		if unicode.IsUpper(c) || unicode.IsTitle(c) {
+			repl[i] = unicode.ToUpper(repl[i])
+		} else if unicode.IsLower(c) {
+			repl[i] = unicode.ToLower(repl[i])
+		}
+	}
+	return string(repl)
+}
+
+/*
+quoteMeta backslashes the ASCII characters other than letters, digits and '_', like Perl's quotemeta
+*/
+func quoteMeta(text string) string {
+	sb := strings.Builder{}
+	sb.Grow(2 * len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c < utf8.RuneSelf && !(c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCaseModification(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("single characters", t, func() {
+		runReplacementTest("kalle ankka", `s/(\w+)/\u$1/g`, "Kalle Ankka")
+		runReplacementTest("KALLE ANKKA", `s/(\w+)/\l$1/g`, "kALLE aNKKA")
+		runReplacementTest("kalle", `s/kalle/\uankka/`, "Ankka")
+		runReplacementTest("kalle", `s/(x?)kalle/\u$1ankka/`, "Ankka")
+	})
+	Convey("until \\E", t, func() {
+		runReplacementTest("kalle ankka", `s/(\w+) (\w+)/\U$1\E $2/`, "KALLE ankka")
+		runReplacementTest("KALLE ANKKA", `s/(\w+) (\w+)/\L$1\E $2/`, "kalle ANKKA")
+		runReplacementTest("kalle ankka", `s/(\w+) (\w+)/\U$1 $2/`, "KALLE ANKKA")
+		runReplacementTest("Kalle", `s/(\w+)/\F$1/`, "kalle")
+	})
+	Convey("titlecasing names, under /g per match", t, func() {
+		runReplacementTest("kALLE aNKKA", `s/(\w+)/\u\L$1/g`, "Kalle Ankka")
+		runReplacementTest("kALLE aNKKA", `s/(\w+)/\L\u$1/g`, "Kalle Ankka")
+		runReplacementTest("kalle ankka", `s/(\w+)/\U$1/`, "KALLE ankka")
+	})
+	Convey("nesting", t, func() {
+		runReplacementTest("kalle", `s/(\w+)/\Ua\Lb\Ec/`, "Abc")
+		runReplacementTest("a.b", `s/(.*)/\Q$1\E $1/`, `a\.b a.b`)
+		runReplacementTest("a.b", `s/(.*)/\U\Q$1\E$1\E$1/`, `A\.BA.Ba.b`)
+	})
+	Convey("Unicode", t, func() {
+		runReplacementTest("ääkkönen", `s/(.+)/\u$1/`, "Ääkkönen")
+		runReplacementTest("straße", `s/(.+)/\U$1/`, "STRASSE")
+		runReplacementTest("STRAẞE", `s/(.+)/\F$1/`, "strasse")
+		runReplacementTest("ǆemal", `s/(.+)/\u$1/`, "ǅemal")
+		runReplacementTest("ΣΊΣΥΦΟΣ", `s/(.+)/\L$1/`, "σίσυφοσ")
+	})
+}
+
+func TestPreserveCase(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("the shape of the matched text", t, func() {
+		runReplacementTest("colour Colour COLOUR", `s/colour/color/gik`, "color Color COLOR")
+		runReplacementTest("cOlOuR", `s/colour/Color/ik`, "Color") // mixed case, different lengths
+		runReplacementTest("a A", `s/a/bee/gik`, "bee Bee")
+		runReplacementTest("ABC", `s/abc/x/ik`, "X")
+		runReplacementTest("123", `s/\d+/abc/k`, "abc")
+		runReplacementTest("Kalle", `s/kalle//ik`, "")
+	})
+	Convey("character by character for equal lengths", t, func() {
+		runReplacementTest("tEsT", `s/test/best/ik`, "bEsT")
+		runReplacementTest("tEsT", `s/test/tests/ik`, "tests")
+		runReplacementTest("a-B", `s/a-b/x_y/ik`, "x_Y")
+	})
+	Convey("with captures, expressions and callbacks", t, func() {
+		runReplacementTest("Kalle Ankka", `s/(?<first>\w+) (\w+)/${first}son/k`, "Kalleson")
+		runReplacementTest("KALLE", `s/(\w+)/lc($1) . "x"/ek`, "KALLEX")
+		str := "Kalle"
+		Se(&str, `s/kalle//eik`, func(m *RE) string { return "ANKKA" })
+		So(str, ShouldEqual, "Ankka")
+	})
+	Convey("Unicode", t, func() {
+		runReplacementTest("Straße", `s/straße/gasse/ik`, "Gasse")
+		runReplacementTest("ÄÄKKÖNEN", `s/ääkkönen/straße/ik`, "STRASSE")
+		runReplacementTest("ǅemal", `s/ǆemal/ǆamal/ik`, "ǅamal")
+	})
+	Convey("the k flag is only valid for s///", t, func() {
+		runErrorTest(`m/a/k`, ErrSyntax)
+		runErrorTest(`tr/a/b/k`, ErrSyntax)
+	})
+}
@@ -0,0 +1,95 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+/*
+Chained is a string run through a sequence of substitutions and transliterations, see Chain.
+*/
+type Chained struct {
+	Steps   []*RE // the RE of each step, with the captures and the Matches of that step
+	Matches int   // the Matches of all the steps added up
+
+	session *Session
+	str     string
+	err     error
+}
+
+/*
+Chain starts a sequence of substitutions and transliterations on a copy of the string, the string itself is never modified.
+
+	str := Chain("kalle ankka").S(`s/a/4/g`).S(`s/e/3/`).Tr(`tr/a-z/A-Z/`).String() // "K4LL3 4NKK4"
+
+The first invalid needle or failing replacement expression stops the chain, the later steps are skipped. Check Err before trusting String.
+*/
+func Chain(str string) *Chained {
+	return defaultSession.Chain(str)
+}
+
+func (self *Session) Chain(str string) *Chained {
+	return &Chained{session: self, str: str}
+}
+
+/*
+S substitutes like S, the r flag makes no difference.
+*/
+func (c *Chained) S(needle string) *Chained {
+	return c.step(func(str *string) (*RE, error) {
+		return c.session.SeE(str, needle, nil)
+	})
+}
+
+/*
+Se substitutes with the callback like Se.
+*/
+func (c *Chained) Se(needle string, fn func(m *RE) string) *Chained {
+	return c.step(func(str *string) (*RE, error) {
+		return c.session.SeE(str, needle, fn)
+	})
+}
+
+/*
+Tr transliterates like Tr, the r flag makes no difference.
+*/
+func (c *Chained) Tr(needle string) *Chained {
+	return c.step(func(str *string) (*RE, error) {
+		return c.session.TrE(str, needle)
+	})
+}
+
+func (c *Chained) step(op func(str *string) (*RE, error)) *Chained {
+	if c.err != nil {
+		return c
+	}
+	r, err := op(&c.str)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	if r.r || (r.tr != nil && r.tr.r) {
+		c.str = r.Result
+	}
+	c.Steps = append(c.Steps, r)
+	c.Matches += r.Matches
+	return c
+}
+
+/*
+String is the string after the steps run so far.
+*/
+func (c *Chained) String() string {
+	return c.str
+}
+
+/*
+Err is the error which stopped the chain, nil if all the steps ran.
+*/
+func (c *Chained) Err() error {
+	return c.err
+}
@@ -0,0 +1,96 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleChain() {
+	c := Chain("kalle ankka").S(`s/(a)/4/g`).S(`s/e/3/`).Tr(`tr/a-z/A-Z/`)
+	fmt.Println(c.String(), c.Matches, c.Steps[0].Matches)
+	// Output: K4LL3 4NKK4 10 3
+}
+
+func TestNonDestructive(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("the r flag leaves the haystack untouched", t, func() {
+		str := "kalle ankka"
+		So(R(&str, `s/(a)/4/gr`), ShouldBeTrue)
+		So(str, ShouldEqual, "kalle ankka")
+		So(R0.Result, ShouldEqual, "k4lle 4nkk4")
+		So(R0.Matches, ShouldEqual, 3)
+		So(R0.S, ShouldResemble, []string{"", "a", "a", "a"})
+
+		r := Sr(&str, `s/x/y/r`)
+		So(r.Matches, ShouldEqual, 0)
+		So(r.Result, ShouldEqual, "kalle ankka")
+
+		r, err := SE(&str, `s/\w+/uc($&)/er`)
+		So(err, ShouldBeNil)
+		So(r.Result, ShouldEqual, "KALLE ankka")
+		So(str, ShouldEqual, "kalle ankka")
+	})
+	Convey("the r flag is only valid for s/// and tr///", t, func() {
+		runErrorTest(`m/a/r`, ErrSyntax)
+		runErrorPositionTest(`m/a/gr`, ComponentFlags, 5)
+	})
+}
+
+func TestChain(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("the steps run in order on a copy", t, func() {
+		str := "2021-03-04"
+		c := Chain(str).S(`s/(\d+)-(\d+)-(\d+)/$3.$2.$1/`).S(`s/\.0/./g`).Tr(`tr/./\//`)
+		So(c.Err(), ShouldBeNil)
+		So(c.String(), ShouldEqual, "04/3/2021")
+		So(str, ShouldEqual, "2021-03-04")
+		So(len(c.Steps), ShouldEqual, 3)
+		So(c.Steps[0].S, ShouldResemble, []string{"", "2021", "03", "04"})
+		So(c.Steps[1].Matches, ShouldEqual, 1)
+		So(c.Steps[2].Matches, ShouldEqual, 2)
+		So(c.Matches, ShouldEqual, 4)
+		So(R0, ShouldEqual, c.Steps[2])
+	})
+	Convey("the r flag makes no difference", t, func() {
+		So(Chain("abc").S(`s/a/x/r`).Tr(`tr/b/y/r`).S(`s/c/z/`).String(), ShouldEqual, "xyz")
+	})
+	Convey("callbacks and expressions", t, func() {
+		c := Chain("1 2 3").S(`s/\d/$& * 2/eg`).Se(`s/(\d+)//e`, func(m *RE) string { return "<" + m.S[1] + ">" })
+		So(c.String(), ShouldEqual, "<2> 4 6")
+		c = Chain("1 2 3").Se(`s/(\d+)//eg`, func(m *RE) string { return "<" + m.S[1] + ">" })
+		So(c.String(), ShouldEqual, "<1> <2> <3>")
+	})
+	Convey("the first error stops the chain", t, func() {
+		c := Chain("kalle").S(`s/k/K/`).S(`s/(a/b/`).Tr(`tr/a-z/A-Z/`)
+		So(errors.Is(c.Err(), ErrSyntax), ShouldBeTrue)
+		So(c.String(), ShouldEqual, "Kalle")
+		So(len(c.Steps), ShouldEqual, 1)
+
+		c = Chain("kalle").S(`m/k/`)
+		So(errors.Is(c.Err(), ErrSyntax), ShouldBeTrue)
+		c = Chain("kalle").Tr(`s/k/K/`)
+		So(errors.Is(c.Err(), ErrSyntax), ShouldBeTrue)
+		c = Chain("kalle").S(`s/k/1 \/ 0/e`)
+		So(errors.Is(c.Err(), ErrEvaluation), ShouldBeTrue)
+		So(c.String(), ShouldEqual, "kalle")
+	})
+	Convey("Sessions", t, func() {
+		sess := NewSession()
+		sess.Flags = "i"
+		c := sess.Chain("KALLE").S(`s/k/c/`)
+		So(c.String(), ShouldEqual, "cALLE")
+		So(sess.Last, ShouldEqual, c.Steps[0])
+	})
+}
@@ -0,0 +1,144 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Sentinel errors returned by the error-returning API (ME, SE, SsE, RrE, Compile).
+ErrEvaluation is the only one raised while substituting instead of while parsing.
+Test for them with errors.Is, the concrete type is always *ParseError.
+*/
+var (
+	ErrSyntax             = errors.New("syntax error")                    // the needle or the resulting Go regexp is malformed
+	ErrMissingTerminator  = errors.New("missing terminator")              // a component of the needle is not closed by the separator
+	ErrUnsupportedFeature = errors.New("unsupported feature")             // valid Perl, but not (yet) supported by go-re or the Go regexp engine
+	ErrTrailingAfterFlags = errors.New("trailing characters after flags") // something else than flags follows the last separator
+	ErrEvaluation         = errors.New("evaluation error")                // the replacement expression of s///e failed for a match
+)
+
+/*
+Component of the needle, eg. in `s/match/replacement/flags`
+*/
+type Component string
+
+const (
+	ComponentMode        Component = "mode"
+	ComponentMatch       Component = "match"
+	ComponentReplacement Component = "replacement"
+	ComponentFlags       Component = "flags"
+)
+
+/*
+ParseError describes why a needle could not be turned into a *RE.
+
+	if _, err := ME(haystack, needle); errors.Is(err, ErrMissingTerminator) {
+		fmt.Println(err.(*ParseError).Pretty())
+	}
+
+Offset always refers to the needle as written by the user, not to the Go regexp it was rewritten to.
+The underlying *syntax.Error from the Go regexp compiler, if any, is reachable with errors.As.
+*/
+type ParseError struct {
+	Needle    string    // the needle as given by the caller
+	Component Component // which part of the needle is at fault
+	Offset    int       // byte offset of the fault in Needle
+	Err       error     // one of the Err* sentinels
+	Msg       string    // human readable details
+	Cause     error     // the underlying error, eg. *syntax.Error from regexp.Compile
+}
+
+func (e *ParseError) Error() string {
+	line, col := e.Position()
+	where := fmt.Sprintf("%s at %d:%d", e.Component, line, col)
+	if e.Msg == "" {
+		return fmt.Sprintf("re: %s in %s of needle '%s'", e.Err, where, e.Needle)
+	}
+	return fmt.Sprintf("re: %s: %s in %s of needle '%s'", e.Err, e.Msg, where, e.Needle)
+}
+
+/*
+Position returns the 1-based line and column (in runes) of Offset, needles using the x flag often span multiple lines.
+*/
+func (e *ParseError) Position() (line int, col int) {
+	before := e.Needle[:e.clampedOffset()]
+	line = strings.Count(before, "\n") + 1
+	col = utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return line, col
+}
+
+/*
+Pretty renders the error followed by the offending line of the needle and a caret under the fault
+
+	re: syntax error: error parsing regexp: missing closing ): `(?P<x>\d+` in match at 1:3 of needle 'm/(?P<x>\d+/xg'
+	m/(?P<x>\d+/xg
+	  ^
+*/
+func (e *ParseError) Pretty() string {
+	offset := e.clampedOffset()
+	start := strings.LastIndexByte(e.Needle[:offset], '\n') + 1
+	end := strings.IndexByte(e.Needle[offset:], '\n')
+	if end < 0 {
+		end = len(e.Needle)
+	} else {
+		end += offset
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(e.Error())
+	sb.WriteByte('\n')
+	sb.WriteString(e.Needle[start:end])
+	sb.WriteByte('\n')
+	for _, c := range e.Needle[start:offset] {
+		if c == '\t' {
+			sb.WriteByte('\t') // keep the caret aligned with tab-indented needles
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('^')
+	return sb.String()
+}
+
+func (e *ParseError) clampedOffset() int {
+	if e.Offset < 0 {
+		return 0
+	}
+	if e.Offset > len(e.Needle) {
+		return len(e.Needle)
+	}
+	return e.Offset
+}
+
+func (e *ParseError) Unwrap() error {
+	if e.Cause != nil {
+		return e.Cause
+	}
+	return e.Err
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == e.Err
+}
+
+func newParseError(r *RE, sentinel error, component Component, offset int, format string, args ...interface{}) *ParseError {
+	return &ParseError{
+		Needle:    r._orig,
+		Component: component,
+		Offset:    offset,
+		Err:       sentinel,
+		Msg:       fmt.Sprintf(format, args...),
+	}
+}
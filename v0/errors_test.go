@@ -0,0 +1,113 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"regexp/syntax"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrors(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("sentinel errors", t, func() {
+		runErrorTest(``, ErrSyntax)
+		runErrorTest(`m`, ErrMissingTerminator)
+		runErrorTest(`m/kalle`, ErrMissingTerminator)
+		runErrorTest(`s/kalle/ankka`, ErrMissingTerminator)
+		runErrorTest(`m/kalle\`, ErrMissingTerminator)
+		runErrorTest(`m/(kalle/`, ErrSyntax)
+		runErrorTest(`m/kalle/q`, ErrSyntax)
+		runErrorTest(`m/kalle/a`, ErrUnsupportedFeature)
+		runErrorTest(`m/kalle/e`, ErrSyntax)
+		runErrorTest(`m/ka(?=lle)/`, ErrUnsupportedFeature)
+		runErrorTest(`m/(?<=k)alle/`, ErrUnsupportedFeature)
+		runErrorTest(`tr/a-z/A-Z/x`, ErrSyntax)
+		runErrorTest(`m/kalle/g/`, ErrTrailingAfterFlags)
+		runErrorTest(`m/kalle/g;`, ErrTrailingAfterFlags)
+		runErrorTest(`m/kalle/g # comments need the x flag`, ErrTrailingAfterFlags)
+	})
+	Convey("error-returning variants", t, func() {
+		r, err := ME("kalle ankka", `m/(a.)/g`)
+		So(err, ShouldBeNil)
+		So(r.S, ShouldResemble, []string{"", "al", "an"})
+
+		str := "kalle ankka"
+		r, err = SE(&str, `s/(a.)/--/g`)
+		So(err, ShouldBeNil)
+		So(str, ShouldEqual, "k--le --kka")
+
+		str = "kalle ankka"
+		r, err = SE(&str, `s/(a./--/g`)
+		So(r, ShouldBeNil)
+		So(str, ShouldEqual, "kalle ankka")
+
+		str, err = SsE("kalle ankka", `s/kalle/minni/ # x makes this a comment`)
+		So(errors.Is(err, ErrTrailingAfterFlags), ShouldBeTrue)
+		So(str, ShouldEqual, "kalle ankka")
+
+		r, err = RrE(&str, `s/kalle/minni/x # x makes this a comment`)
+		So(err, ShouldBeNil)
+		So(r.Matches, ShouldEqual, 1)
+		So(str, ShouldEqual, "minni ankka")
+
+		_, err = Compile(`m/(?P<name>\w+/`)
+		var serr *syntax.Error
+		So(errors.As(err, &serr), ShouldBeTrue)
+		So(serr.Code, ShouldEqual, syntax.ErrMissingParen)
+		var perr *ParseError
+		So(errors.As(err, &perr), ShouldBeTrue)
+		So(perr.Needle, ShouldEqual, `m/(?P<name>\w+/`)
+	})
+	Convey("error positions refer to the needle as written", t, func() {
+		runErrorPositionTest(`m/(?P<x>\d+/xg`, ComponentMatch, 2)
+		runErrorPositionTest("m/\n\t^ (a) # comment\n\tb{2,1}\n/x", ComponentMatch, 22)
+		runErrorPositionTest(`m/(?i)a)b/`, ComponentMatch, 7)
+		runErrorPositionTest(`m/a\/b\q/`, ComponentMatch, 6)
+		runErrorPositionTest(`s/a/b/gq`, ComponentFlags, 7)
+		runErrorPositionTest(`s/a/b`, ComponentReplacement, 5)
+		runErrorPositionTest(`s`, ComponentMode, 1)
+	})
+	Convey("pretty printing", t, func() {
+		_, err := Compile("m/\n\t^ (a) # comment\n\tb{2,1}\n/x")
+		So(err.(*ParseError).Pretty(), ShouldEqual, "re: syntax error: error parsing regexp: invalid repeat count: `{2,1}` in match at 3:3 of needle 'm/\n\t^ (a) # comment\n\tb{2,1}\n/x'\n"+
+			"\tb{2,1}\n"+
+			"\t ^")
+	})
+	Convey("must flavour panics with a *ParseError", t, func() {
+		So(func() { M("kalle", `m/kalle`) }, ShouldPanic)
+		defer func() {
+			err, _ := recover().(error)
+			So(errors.Is(err, ErrMissingTerminator), ShouldBeTrue)
+		}()
+		M("kalle", `m/kalle`)
+	})
+}
+
+func runErrorTest(needle string, sentinel error) {
+	Convey(`"`+needle+`" fails with "`+sentinel.Error()+`"`, func() {
+		r, err := Compile(needle)
+		So(r, ShouldBeNil)
+		So(errors.Is(err, sentinel), ShouldBeTrue)
+	})
+}
+
+func runErrorPositionTest(needle string, component Component, offset int) {
+	Convey(fmt.Sprintf(`"%s" fails in %s at offset %d`, needle, component, offset), func() {
+		_, err := Compile(needle)
+		var perr *ParseError
+		So(errors.As(err, &perr), ShouldBeTrue)
+		So(perr.Component, ShouldEqual, component)
+		So(perr.Offset, ShouldEqual, offset)
+	})
+}
@@ -0,0 +1,824 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxExprSteps = 10000    // how many operations evaluating a replacement expression may take
+const maxExprLength = 1 << 20 // how long strings a replacement expression may build
+
+/*
+exprValue is a Perl scalar, a string or a number, converted to the other on demand.
+*/
+type exprValue struct {
+	s     string
+	n     float64
+	isNum bool
+}
+
+func exprString(s string) exprValue  { return exprValue{s: s} }
+func exprNumber(n float64) exprValue { return exprValue{n: n, isNum: true} }
+func exprBool(b bool) exprValue {
+	if b {
+		return exprNumber(1)
+	}
+	return exprString("")
+}
+
+func (v exprValue) str() string {
+	if !v.isNum {
+		return v.s
+	}
+	switch {
+	case math.IsInf(v.n, 1):
+		return "Inf"
+	case math.IsInf(v.n, -1):
+		return "-Inf"
+	case math.IsNaN(v.n):
+		return "NaN"
+	}
+	if v.n == math.Trunc(v.n) && math.Abs(v.n) < 1e15 {
+		return strconv.FormatInt(int64(v.n), 10)
+	}
+	return strconv.FormatFloat(v.n, 'g', 15, 64)
+}
+
+/*
+num converts like Perl, from the leading number of the string, eg. "12abc" is 12 and "abc" is 0
+*/
+func (v exprValue) num() float64 {
+	if v.isNum {
+		return v.n
+	}
+	s := strings.TrimLeft(v.s, " \t\n\r\f\v")
+	end := 0
+	if end < len(s) && (s[end] == '+' || s[end] == '-') {
+		end++
+	}
+	digits := func() int {
+		start := end
+		for end < len(s) && '0' <= s[end] && s[end] <= '9' {
+			end++
+		}
+		return end - start
+	}
+	n := digits()
+	if end < len(s) && s[end] == '.' {
+		end++
+		n += digits()
+	}
+	if n == 0 {
+		return 0
+	}
+	if end < len(s) && (s[end] == 'e' || s[end] == 'E') {
+		mantissa := end
+		end++
+		if end < len(s) && (s[end] == '+' || s[end] == '-') {
+			end++
+		}
+		if digits() == 0 {
+			end = mantissa
+		}
+	}
+	f, _ := strconv.ParseFloat(s[:end], 64)
+	return f
+}
+
+func (v exprValue) truth() bool {
+	if v.isNum {
+		return v.n != 0
+	}
+	return v.s != "" && v.s != "0"
+}
+
+/*
+exprNode is a node of a parsed replacement expression.
+*/
+type exprNode struct {
+	op    string            // "value", "string" for interpolated strings and variables, "call", "?:", or the operator
+	value exprValue         // the literal
+	parts []replacementPart // the interpolated string or the variable
+	name  string            // the function called
+	args  []*exprNode       // the operands or arguments
+	pos   int               // offset in the needle, for error reporting
+}
+
+/*
+exprParser is a recursive descent parser of the replacement expression language of s///e.
+
+	expr    := or ( '?' expr ':' expr )?
+	or      := and ( '||' and )*
+	and     := eq ( '&&' eq )*
+	eq      := rel ( ( '==' | '!=' | '<=>' | 'eq' | 'ne' | 'cmp' ) rel )*
+	rel     := add ( ( '<' | '>' | '<=' | '>=' | 'lt' | 'gt' | 'le' | 'ge' ) add )*
+	add     := mul ( ( '+' | '-' | '.' ) mul )*
+	mul     := unary ( ( '*' | '/' | '%' | 'x' ) unary )*
+	unary   := ( '!' | '-' | '+' ) unary | pow
+	pow     := primary ( '**' unary )?
+	primary := number | 'string' | "string" | $variable | function '(' expr, ... ')' | '(' expr ')'
+*/
+type exprParser struct {
+	r   *RE
+	src string
+	pos []int // offset in the needle of each byte of src
+	i   int
+}
+
+/*
+exprFunctions are the functions of the replacement expression language, with their minimum and maximum number of arguments.
+*/
+var exprFunctions = map[string][2]int{
+	"lc":      {1, 1},
+	"uc":      {1, 1},
+	"lcfirst": {1, 1},
+	"ucfirst": {1, 1},
+	"length":  {1, 1},
+	"int":     {1, 1},
+	"abs":     {1, 1},
+	"substr":  {2, 3},
+	"sprintf": {1, -1},
+}
+
+/*
+exprParse parses the whole source into an expression.
+*/
+func exprParse(r *RE, src string, pos []int) (*exprNode, error) {
+	p := &exprParser{r: r, src: src, pos: pos}
+	p.space()
+	if p.i >= len(p.src) {
+		return &exprNode{op: "value", value: exprString("")}, nil // like Perl's undef
+	}
+	node, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.src) {
+		return nil, p.errorf("unexpected '%s'", p.src[p.i:])
+	}
+	return node, nil
+}
+
+func (p *exprParser) offset() int {
+	if p.i < len(p.pos) {
+		return p.pos[p.i]
+	}
+	if len(p.pos) > 0 {
+		return p.pos[len(p.pos)-1] + 1
+	}
+	return p.r.sEnd
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return newParseError(p.r, ErrSyntax, ComponentReplacement, p.offset(), format, args...)
+}
+
+func (p *exprParser) space() {
+	for p.i < len(p.src) && strings.IndexByte(" \t\n\r\f\v", p.src[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+/*
+accept consumes the first of the operators found next, a word operator must not be followed by a letter.
+*/
+func (p *exprParser) accept(ops ...string) (string, int) {
+	for _, op := range ops {
+		if !strings.HasPrefix(p.src[p.i:], op) {
+			continue
+		}
+		end := p.i + len(op)
+		if c := op[0]; 'a' <= c && c <= 'z' && end < len(p.src) && isWordByte(p.src[end]) {
+			continue
+		}
+		if op == "*" && strings.HasPrefix(p.src[p.i:], "**") {
+			continue
+		}
+		if (op == "<" || op == ">") && end < len(p.src) && p.src[end] == '=' || op == "<=" && end < len(p.src) && p.src[end] == '>' {
+			continue
+		}
+		start := p.i
+		p.i = end
+		p.space()
+		return op, start
+	}
+	return "", p.i
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func (p *exprParser) binary(next func() (*exprNode, error), ops ...string) (*exprNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, start := p.accept(ops...)
+		if op == "" {
+			return left, nil
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprNode{op: op, args: []*exprNode{left, right}, pos: p.posAt(start)}
+	}
+}
+
+func (p *exprParser) posAt(i int) int {
+	saved := p.i
+	p.i = i
+	defer func() { p.i = saved }()
+	return p.offset()
+}
+
+func (p *exprParser) expr() (*exprNode, error) {
+	cond, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	op, start := p.accept("?")
+	if op == "" {
+		return cond, nil
+	}
+	then, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if op, _ := p.accept(":"); op == "" {
+		return nil, p.errorf("missing ':' of the '?' operator")
+	}
+	otherwise, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &exprNode{op: "?:", args: []*exprNode{cond, then, otherwise}, pos: p.posAt(start)}, nil
+}
+
+func (p *exprParser) or() (*exprNode, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *exprParser) and() (*exprNode, error) {
+	return p.binary(p.eq, "&&")
+}
+
+func (p *exprParser) eq() (*exprNode, error) {
+	return p.binary(p.rel, "==", "!=", "<=>", "eq", "ne", "cmp")
+}
+
+func (p *exprParser) rel() (*exprNode, error) {
+	return p.binary(p.add, "<=", ">=", "<", ">", "lt", "gt", "le", "ge")
+}
+
+func (p *exprParser) add() (*exprNode, error) {
+	return p.binary(p.mul, "+", "-", ".")
+}
+
+func (p *exprParser) mul() (*exprNode, error) {
+	return p.binary(p.unary, "*", "/", "%", "x")
+}
+
+func (p *exprParser) unary() (*exprNode, error) {
+	op, start := p.accept("!", "-", "+")
+	if op == "" {
+		return p.pow()
+	}
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &exprNode{op: "unary" + op, args: []*exprNode{operand}, pos: p.posAt(start)}, nil
+}
+
+func (p *exprParser) pow() (*exprNode, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	op, start := p.accept("**")
+	if op == "" {
+		return base, nil
+	}
+	exponent, err := p.unary() // right associative, and binds tighter than the unary minus on its left
+	if err != nil {
+		return nil, err
+	}
+	return &exprNode{op: op, args: []*exprNode{base, exponent}, pos: p.posAt(start)}, nil
+}
+
+func (p *exprParser) primary() (*exprNode, error) {
+	if p.i >= len(p.src) {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	start := p.i
+	node := &exprNode{pos: p.offset()}
+	switch c := p.src[p.i]; {
+	case c == '(':
+		p.i++
+		p.space()
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if op, _ := p.accept(")"); op == "" {
+			return nil, p.errorf("missing ')'")
+		}
+		return inner, nil
+	case '0' <= c && c <= '9' || (c == '.' && p.i+1 < len(p.src) && '0' <= p.src[p.i+1] && p.src[p.i+1] <= '9'):
+		for p.i < len(p.src) && (isWordByte(p.src[p.i]) || p.src[p.i] == '.' ||
+			((p.src[p.i] == '+' || p.src[p.i] == '-') && (p.src[p.i-1] == 'e' || p.src[p.i-1] == 'E'))) {
+			p.i++
+		}
+		literal := strings.Replace(p.src[start:p.i], "_", "", -1)
+		var n float64
+		var err error
+		if strings.HasPrefix(literal, "0x") || strings.HasPrefix(literal, "0b") {
+			var i int64
+			i, err = strconv.ParseInt(literal, 0, 64)
+			n = float64(i)
+		} else {
+			n, err = strconv.ParseFloat(literal, 64)
+		}
+		if err != nil {
+			p.i = start
+			return nil, p.errorf("invalid number '%s'", literal)
+		}
+		node.op, node.value = "value", exprNumber(n)
+	case c == '\'' || c == '"':
+		end := p.i + 1
+		for ; end < len(p.src) && p.src[end] != c; end++ {
+			if p.src[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(p.src) {
+			return nil, newParseError(p.r, ErrMissingTerminator, ComponentReplacement, node.pos, "unterminated string")
+		}
+		parts, err := replacementTemplate(p.r, p.src[p.i+1:end], p.pos[p.i+1:end], c == '\'')
+		if err != nil {
+			return nil, err
+		}
+		node.op, node.parts = "string", parts
+		p.i = end + 1
+	case c == '$' && p.i+1 < len(p.src):
+		part, end, err := replacementVariable(p.r, p.src, p.i, p.pos)
+		if err != nil {
+			return nil, err
+		}
+		if end == p.i+1 || part.op == replaceLiteral {
+			return nil, p.errorf("invalid variable")
+		}
+		node.op, node.parts = "string", []replacementPart{part}
+		p.i = end
+	case 'a' <= c && c <= 'z':
+		for p.i < len(p.src) && isWordByte(p.src[p.i]) {
+			p.i++
+		}
+		name := p.src[start:p.i]
+		arity, ok := exprFunctions[name]
+		if !ok {
+			p.i = start
+			return nil, p.errorf("unknown function '%s'", name)
+		}
+		p.space()
+		if op, _ := p.accept("("); op == "" {
+			return nil, p.errorf("missing '(' after '%s'", name)
+		}
+		node.op, node.name = "call", name
+		for {
+			if op, _ := p.accept(")"); op != "" {
+				break
+			}
+			if len(node.args) > 0 {
+				if op, _ := p.accept(","); op == "" {
+					return nil, p.errorf("missing ',' or ')' in the arguments of '%s'", name)
+				}
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			node.args = append(node.args, arg)
+		}
+		if len(node.args) < arity[0] || (arity[1] >= 0 && len(node.args) > arity[1]) {
+			p.i = start
+			return nil, p.errorf("wrong number of arguments for '%s'", name)
+		}
+	default:
+		return nil, p.errorf("unexpected '%c'", c)
+	}
+	p.space()
+	return node, nil
+}
+
+/*
+exprEnv is what a replacement expression is evaluated against.
+*/
+type exprEnv struct {
+	r        *RE
+	haystack string
+	match    []int
+	steps    int
+}
+
+func (env *exprEnv) errorf(node *exprNode, format string, args ...interface{}) error {
+	return newParseError(env.r, ErrEvaluation, ComponentReplacement, node.pos, format, args...)
+}
+
+func (env *exprEnv) eval(node *exprNode) (exprValue, error) {
+	env.steps++
+	if env.steps > maxExprSteps {
+		return exprValue{}, env.errorf(node, "more than %d steps", maxExprSteps)
+	}
+
+	switch node.op {
+	case "value":
+		return node.value, nil
+	case "string":
+		text := expandParts(nil, node.parts, env.r, env.haystack, env.match)
+		if len(text) > maxExprLength {
+			return exprValue{}, env.errorf(node, "string longer than %d bytes", maxExprLength)
+		}
+		return exprString(string(text)), nil
+	case "?:":
+		cond, err := env.eval(node.args[0])
+		if err != nil {
+			return cond, err
+		}
+		if cond.truth() {
+			return env.eval(node.args[1])
+		}
+		return env.eval(node.args[2])
+	case "&&", "||":
+		left, err := env.eval(node.args[0])
+		if err != nil || left.truth() == (node.op == "||") {
+			return left, err
+		}
+		return env.eval(node.args[1])
+	case "call":
+		return env.call(node)
+	}
+
+	args := make([]exprValue, len(node.args))
+	for i, arg := range node.args {
+		var err error
+		if args[i], err = env.eval(arg); err != nil {
+			return args[i], err
+		}
+	}
+	switch node.op {
+	case "unary!":
+		return exprBool(!args[0].truth()), nil
+	case "unary-":
+		if !args[0].isNum && args[0].s != "" && !strings.ContainsAny(args[0].s[:1], "+-.0123456789 \t\n") {
+			return exprString("-" + args[0].s), nil // Perl's -bareword
+		}
+		return exprNumber(-args[0].num()), nil
+	case "unary+":
+		return args[0], nil
+	}
+
+	a, b := args[0], args[1]
+	switch node.op {
+	case "+":
+		return exprNumber(a.num() + b.num()), nil
+	case "-":
+		return exprNumber(a.num() - b.num()), nil
+	case "*":
+		return exprNumber(a.num() * b.num()), nil
+	case "/":
+		if b.num() == 0 {
+			return exprValue{}, env.errorf(node, "illegal division by zero")
+		}
+		return exprNumber(a.num() / b.num()), nil
+	case "%":
+		l, m := math.Trunc(a.num()), math.Trunc(b.num())
+		if m == 0 {
+			return exprValue{}, env.errorf(node, "illegal modulus zero")
+		}
+		mod := math.Mod(l, m)
+		if mod != 0 && (mod < 0) != (m < 0) {
+			mod += m // the sign of the result follows the right operand
+		}
+		return exprNumber(mod), nil
+	case "**":
+		return exprNumber(math.Pow(a.num(), b.num())), nil
+	case ".":
+		if len(a.str())+len(b.str()) > maxExprLength {
+			return exprValue{}, env.errorf(node, "string longer than %d bytes", maxExprLength)
+		}
+		return exprString(a.str() + b.str()), nil
+	case "x":
+		n := math.Trunc(b.num())
+		if math.IsInf(n, 0) || math.IsNaN(n) {
+			return exprValue{}, env.errorf(node, "invalid repeat count %s", exprNumber(n).str())
+		}
+		if n <= 0 {
+			return exprString(""), nil
+		}
+		if n > maxExprLength || float64(len(a.str()))*n > maxExprLength {
+			return exprValue{}, env.errorf(node, "string longer than %d bytes", maxExprLength)
+		}
+		return exprString(strings.Repeat(a.str(), int(n))), nil
+	case "==":
+		return exprBool(a.num() == b.num()), nil
+	case "!=":
+		return exprBool(a.num() != b.num()), nil
+	case "<":
+		return exprBool(a.num() < b.num()), nil
+	case ">":
+		return exprBool(a.num() > b.num()), nil
+	case "<=":
+		return exprBool(a.num() <= b.num()), nil
+	case ">=":
+		return exprBool(a.num() >= b.num()), nil
+	case "<=>":
+		switch x, y := a.num(), b.num(); {
+		case x < y:
+			return exprNumber(-1), nil
+		case x > y:
+			return exprNumber(1), nil
+		}
+		return exprNumber(0), nil
+	case "eq":
+		return exprBool(a.str() == b.str()), nil
+	case "ne":
+		return exprBool(a.str() != b.str()), nil
+	case "lt":
+		return exprBool(a.str() < b.str()), nil
+	case "gt":
+		return exprBool(a.str() > b.str()), nil
+	case "le":
+		return exprBool(a.str() <= b.str()), nil
+	case "ge":
+		return exprBool(a.str() >= b.str()), nil
+	case "cmp":
+		return exprNumber(float64(strings.Compare(a.str(), b.str()))), nil
+	}
+	return exprValue{}, env.errorf(node, "unknown operator '%s'", node.op)
+}
+
+func (env *exprEnv) call(node *exprNode) (exprValue, error) {
+	args := make([]exprValue, len(node.args))
+	for i, arg := range node.args {
+		var err error
+		if args[i], err = env.eval(arg); err != nil {
+			return args[i], err
+		}
+	}
+	switch node.name {
+	case "lc":
+		return exprString(caseMap('L', args[0].str())), nil
+	case "uc":
+		return exprString(caseMap('U', args[0].str())), nil
+	case "lcfirst", "ucfirst":
+		s := args[0].str()
+		c, size := utf8.DecodeRuneInString(s)
+		if size == 0 {
+			return exprString(""), nil
+		}
+		return exprString(caseMap(node.name[0], string(c)) + s[size:]), nil
+	case "length":
+		return exprNumber(float64(utf8.RuneCountInString(args[0].str()))), nil
+	case "int":
+		return exprNumber(math.Trunc(args[0].num())), nil
+	case "abs":
+		return exprNumber(math.Abs(args[0].num())), nil
+	case "substr": // the offsets are clamped, so start+length cannot overflow
+		runes := []rune(args[0].str())
+		start := exprInt(args[1].num())
+		if start < 0 {
+			start += len(runes)
+		}
+		if start < 0 || start > len(runes) {
+			return exprString(""), nil // Perl returns undef
+		}
+		end := len(runes)
+		if len(args) > 2 {
+			length := exprInt(args[2].num())
+			if length < 0 {
+				end += length
+			} else if start+length < end {
+				end = start + length
+			}
+		}
+		if end < start {
+			end = start
+		}
+		return exprString(string(runes[start:end])), nil
+	case "sprintf":
+		s, err := exprSprintf(args[0].str(), args[1:])
+		if err != nil {
+			return exprValue{}, env.errorf(node, "%s", err)
+		}
+		return exprString(s), nil
+	}
+	return exprValue{}, env.errorf(node, "unknown function '%s'", node.name)
+}
+
+/*
+exprInt truncates the number into an int, clamped just past maxExprLength so that arithmetic on it cannot overflow, NaN is 0
+*/
+func exprInt(n float64) int {
+	switch {
+	case math.IsNaN(n):
+		return 0
+	case n > maxExprLength:
+		return maxExprLength + 1
+	case n < -maxExprLength:
+		return -maxExprLength - 1
+	}
+	return int(math.Trunc(n))
+}
+
+/*
+infNaN formats Inf, -Inf and NaN for the numeric conversions of exprSprintf like Perl does, padded with spaces to the width
+*/
+func infNaN(n float64, flags string, width int) string {
+	s := exprNumber(n).str()
+	if n > 0 && strings.Contains(flags, "+") {
+		s = "+" + s
+	}
+	if strings.Contains(flags, "-") || width < 0 {
+		return fmt.Sprintf("%-*s", max(width, -width), s)
+	}
+	return fmt.Sprintf("%*s", width, s)
+}
+
+/*
+exprSprintf formats like Perl's sprintf, with the conversions %% %c %s %d %i %u %o %x %X %b %B %e %E %f %F %g %G
+*/
+func exprSprintf(format string, args []exprValue) (string, error) {
+	sb := strings.Builder{}
+	next := func() exprValue {
+		if len(args) == 0 {
+			return exprString("")
+		}
+		arg := args[0]
+		args = args[1:]
+		return arg
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			sb.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+		spec := strings.Builder{}
+		spec.WriteByte('%')
+		for i < len(format) && strings.IndexByte("-+ 0#", format[i]) >= 0 {
+			spec.WriteByte(format[i])
+			i++
+		}
+		flags, width := spec.String()[1:], 0
+		for _, part := range []string{"width", "precision"} {
+			if part == "precision" {
+				if i >= len(format) || format[i] != '.' {
+					break
+				}
+				i++
+			}
+			n := 0
+			if i < len(format) && format[i] == '*' {
+				n = exprInt(next().num())
+				i++
+				if part == "precision" && n < 0 {
+					break // Perl ignores a negative precision
+				}
+			} else {
+				for ; i < len(format) && '0' <= format[i] && format[i] <= '9'; i++ {
+					n = 10*n + int(format[i]-'0')
+					if n > maxExprLength {
+						break
+					}
+				}
+			}
+			if n > maxExprLength || -n > maxExprLength {
+				return "", fmt.Errorf("sprintf %s %d too large", part, n)
+			}
+			if part == "precision" {
+				spec.WriteByte('.')
+			}
+			if n != 0 || part == "precision" {
+				spec.WriteString(strconv.Itoa(n))
+			}
+			if part == "width" {
+				width = n
+			}
+		}
+		if i >= len(format) {
+			sb.WriteString(format[start:])
+			break
+		}
+		switch verb := format[i]; verb {
+		case '%':
+			sb.WriteByte('%')
+		case 'c':
+			fmt.Fprintf(&sb, spec.String()+"c", rune(next().num()))
+		case 's':
+			fmt.Fprintf(&sb, spec.String()+"s", next().str())
+		case 'd', 'i', 'u':
+			if n := next().num(); math.IsInf(n, 0) || math.IsNaN(n) {
+				sb.WriteString(infNaN(n, flags, width))
+			} else {
+				fmt.Fprintf(&sb, spec.String()+"d", int64(n))
+			}
+		case 'o', 'x', 'X', 'b', 'B':
+			if verb == 'B' {
+				verb = 'b'
+			}
+			fmt.Fprintf(&sb, spec.String()+string(verb), uint64(int64(next().num())))
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			if n := next().num(); math.IsInf(n, 0) || math.IsNaN(n) {
+				sb.WriteString(infNaN(n, flags, width))
+			} else {
+				fmt.Fprintf(&sb, spec.String()+string(verb), n)
+			}
+		default:
+			sb.WriteString(format[start : i+1]) // not a conversion, kept as is
+		}
+		if sb.Len() > maxExprLength {
+			return "", fmt.Errorf("string longer than %d bytes", maxExprLength)
+		}
+	}
+	return sb.String(), nil
+}
+
+/*
+replacementExprParser parses the replacement of s///e into r.expr, a backslash before punctuation, eg. an escaped delimiter, is dropped.
+*/
+func replacementExprParser(r *RE) error {
+	src := strings.Builder{}
+	pos := make([]int, 0, len(r.sPos))
+	s := *r.s
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == '\\' && i+1 < len(s):
+			src.WriteString(s[i : i+2]) // the escapes of strings are handled by their parser
+			pos = append(pos, r.sPos[i], r.sPos[i+1])
+			i++
+			continue
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == 0 && c == '\\' && i+1 < len(s) && !isWordByte(s[i+1]):
+			i++
+			c = s[i]
+		}
+		src.WriteByte(c)
+		pos = append(pos, r.sPos[i])
+	}
+	node, err := exprParse(r, src.String(), pos)
+	if err != nil {
+		return err
+	}
+	r.expr = node
+	return nil
+}
+
+/*
+evalReplacement evaluates the replacement expression for the match, and the result again as an expression for each additional e flag.
+*/
+func (r *RE) evalReplacement(haystack string, match []int) (string, error) {
+	env := &exprEnv{r: r, haystack: haystack, match: match}
+	value, err := env.eval(r.expr)
+	if err != nil {
+		return "", err
+	}
+	for i := 1; i < r.evals; i++ {
+		src := value.str()
+		pos := make([]int, len(src))
+		for j := range pos {
+			pos[j] = r.sEnd // the code was not in the needle
+		}
+		node, err := exprParse(r, src, pos)
+		if err != nil {
+			perr := err.(*ParseError)
+			perr.Err, perr.Offset, perr.Msg = ErrEvaluation, r.sEnd, fmt.Sprintf("%s, evaluating '%s' again", perr.Msg, src)
+			return "", perr
+		}
+		if value, err = env.eval(node); err != nil {
+			return "", err
+		}
+	}
+	return value.str(), nil
+}
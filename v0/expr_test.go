@@ -0,0 +1,150 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleS_expression() {
+	str := "3 apples and 5 oranges"
+	S(&str, `s/(\d+) (\w+)/$1 * 2 . " " . uc($2)/eg`)
+	fmt.Println(str)
+	// Output: 6 APPLES and 10 ORANGES
+}
+
+func TestExpression(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("arithmetic", t, func() {
+		runReplacementTest("21", `s/\d+/$& * 2/e`, "42")
+		runReplacementTest("7", `s/\d+/$& + 1 - 2 * 3/e`, "2")
+		runReplacementTest("7", `s/\d+/($& + 1) * -2/e`, "-16")
+		runReplacementTest("7", `s/\d+/$& \/ 2/e`, "3.5")
+		runReplacementTest("7", `s{\d+}{$& / 2}e`, "3.5")
+		runReplacementTest("-7", `s/.+/$& % 3/e`, "2")
+		runReplacementTest("7", `s/\d+/-2 ** 2 . ',' . 2 ** 3 ** 2/e`, "-4,512")
+		runReplacementTest("7", `s/\d+/int($& \/ 2) . abs(-1.5)/e`, "31.5")
+		runReplacementTest("7", `s/\d+/0x10 + 1_000 + 1e3 + .5/e`, "2016.5")
+		runReplacementTest("12abc", `s/.+/$& + 1/e`, "13")
+		runReplacementTest("abc", `s/.+/$& + 1/e`, "1")
+		runReplacementTest("1", `s/\d/0.1 + 0.2/e`, "0.3")
+	})
+	Convey("strings", t, func() {
+		runReplacementTest("kalle ankka", `s/(\w+) (\w+)/$2 . ", " . $1/e`, "ankka, kalle")
+		runReplacementTest("kalle ankka", `s/(?<first>\w+) (\w+)/"$2, $+{first}"/e`, "ankka, kalle")
+		runReplacementTest("kalle", `s/\w+/'$&' . "\t$&"/e`, "$&\tkalle")
+		runReplacementTest("kalle", `s/\w+/"-" x 3 . $& x 0/e`, "---")
+	})
+	Convey("functions", t, func() {
+		runReplacementTest("kalle Ankka", `s/(\w+) (\w+)/uc($1) . lc($2)/e`, "KALLEankka")
+		runReplacementTest("kalle Ankka", `s/(\w+) (\w+)/ucfirst($1) . lcfirst($2)/e`, "Kalleankka")
+		runReplacementTest("kalle", `s/\w+/length($&) . length("☺")/e`, "51")
+		runReplacementTest("kalle", `s/\w+/substr($&, 1) . substr($&, -2) . substr($&, 1, 2) . substr($&, 1, -1)/e`, "allelealall")
+		runReplacementTest("kalle", `s/\w+/substr($&, 10) . "|"/e`, "|")
+		runReplacementTest("7 3.14159", `s/(\d+) ([\d.]+)/sprintf("%03d|%.2f|%5s|%-3s|%x|%%|%c", $1, $2, "ab", "c", 255, 65)/e`, "007|3.14|   ab|c  |ff|%|A")
+		runReplacementTest("7", `s/\d/sprintf("%*d|%b|%o|%e", 4, $&, $&, $&, $&)/e`, "   7|111|7|7.000000e+00")
+		runReplacementTest("a", `s/a/sprintf("%.*f|%.*f|%.0f", -1, 1.5, 1, 1.25, 1.5)/e`, "1.500000|1.2|2")
+		runReplacementTest("a", `s/a/substr("abcdef", 1, 1e19) . "|" . substr("abcdef", -1e19, 2) . "|" . substr("abcdef", 2, -1e19)/e`, "bcdef||")
+		runReplacementTest("a", `s/a/2 ** 2000 . ',' . -2 ** 2000 . ',' . (2 ** 2000 - 2 ** 2000)/e`, "Inf,-Inf,NaN")
+		runReplacementTest("a", `s/a/sprintf("%f|%5d|%-5e|%+g|%.2f", 2 ** 2000, -2 ** 2000, 2 ** 2000, 2 ** 2000, 2 ** 2000 - 2 ** 2000)/e`, "Inf| -Inf|Inf  |+Inf|NaN")
+	})
+	Convey("comparisons, logic and ternaries", t, func() {
+		runReplacementTest("5 15", `s/\d+/$& > 10 ? "big" : "small"/eg`, "small big")
+		runReplacementTest("5 15", `s/\d+/$& < 10 && $& != 4 ? "yes" : "no"/eg`, "yes no")
+		runReplacementTest("a b", `s/\w/$& eq "a" ? 1 : $& lt "c" ? 2 : 3/eg`, "1 2")
+		runReplacementTest("x", `s/x/(2 <=> 1) . (1 <=> 2) . ("a" cmp "a")/e`, "1-10")
+		runReplacementTest("x", `s/x/(0 || "") . ("0" || "z") . (1 && "y") . !1 . !0/e`, "zy1")
+		runReplacementTest("x", `s/x/1 == 1.0 ? "num" : "str"/e`, "num")
+		runReplacementTest("x", `s/x/"1" eq "1.0" ? "num" : "str"/e`, "str")
+	})
+	Convey("special variables", t, func() {
+		runReplacementTest("kalle ankka", "s/ /'<' . $` . '|' . $' . '>'/e", "kalle<kalle|ankka>ankka")
+		runReplacementTest("kalle ankka", `s/(a)|(k)/$+/eg`, "kalle ankka")
+		runReplacementTest("kalle ankka", `s/(?<v>[aeiou])/${v} x 2/eg`, "kaallee aankkaa")
+	})
+	Convey("the ee flag evaluates the result again", t, func() {
+		runReplacementTest("2+3 4*5", `s/(\S+)/$1/eeg`, "5 20")
+		runReplacementTest("x", `s/x/'"a" . "b"'/ee`, "ab")
+		runReplacementTest("x", `s/x/'"a" . "b"'/e`, `"a" . "b"`)
+		runReplacementTest("x", `s/x/'1+1'/eee`, "2")
+	})
+	Convey("the x flag and whitespace", t, func() {
+		runReplacementTest("21", `s{ (\d+) }{
+			$1 * 2
+		}ex`, "42")
+	})
+	Convey("Sessions and Patterns", t, func() {
+		str := "1 2"
+		_, err := SE(&str, `s/\d/$& + 1/eg`)
+		So(err, ShouldBeNil)
+		So(str, ShouldEqual, "2 3")
+
+		p := MustQr(`s/\d/$& * 10/e`)
+		str, r := p.SubstAll("1 2")
+		So(str, ShouldEqual, "10 20")
+		So(r.Matches, ShouldEqual, 2)
+		str, _ = MustQr(`m/\d/`).WithReplacement(`$& * 10`).Subst("1 2")
+		So(str, ShouldEqual, "1 * 10 2")
+	})
+	Convey("invalid expressions", t, func() {
+		runErrorTest(`s/a/1 +/e`, ErrSyntax)
+		runErrorTest(`s/a/(1/e`, ErrSyntax)
+		runErrorTest(`s/a/1 ? 2/e`, ErrSyntax)
+		runErrorTest(`s/a/system("ls")/e`, ErrSyntax)
+		runErrorTest(`s/a/uc $&/e`, ErrSyntax)
+		runErrorTest(`s/a/substr("x")/e`, ErrSyntax)
+		runErrorTest(`s/a/"abc/e`, ErrMissingTerminator)
+		runErrorTest(`s/a/1 2/e`, ErrSyntax)
+		runErrorTest(`s/a/$/e`, ErrSyntax)
+		runErrorTest(`s/a/"\x{zz}"/e`, ErrSyntax)
+		runErrorPositionTest(`s/a/1 + foo(2)/e`, ComponentReplacement, 8)
+		runErrorPositionTest(`s/a/$1 +/e`, ComponentReplacement, 8)
+	})
+	Convey("evaluation errors", t, func() {
+		runEvaluationErrorTest("a", `s/a/1 \/ 0/e`, 7)
+		runEvaluationErrorTest("a", `s/a/1 % 0/e`, 6)
+		runEvaluationErrorTest("a", `s/a/"x" x 2e6/e`, 8)
+		runEvaluationErrorTest("x", `s/x/"" x 1e300/e`, 7)
+		runEvaluationErrorTest("x", `s/x/sprintf("%.*f", 1e300, 1)/e`, 4)
+		runEvaluationErrorTest("x", `s/x/"a" x 1e300/e`, 8)
+		runEvaluationErrorTest("x", `s/x/"a" x 2 ** 2000/e`, 8)
+		runEvaluationErrorTest("x", `s/x/"a" x ((-1) ** 0.5)/e`, 8)
+		runEvaluationErrorTest("a", `s/a/sprintf("%9999999d", 1)/e`, 4)
+		runEvaluationErrorTest("a", `s/a/'1 +'/ee`, 9)
+		runEvaluationErrorTest("a", `s/a/'1 % 0'/ee`, 11)
+
+		deep := strings.Repeat("1+", maxExprSteps) + "1"
+		runEvaluationErrorTest("a", `s/a/`+deep+`/e`, -1)
+
+		str := "a b"
+		So(func() { S(&str, `s/\w/1 \/ ($& eq "b" ? 0 : 1)/eg`) }, ShouldPanic)
+		So(str, ShouldEqual, "a b")
+	})
+}
+
+func runEvaluationErrorTest(haystack string, needle string, offset int) {
+	Convey(fmt.Sprintf(`"%s" fails evaluating for "%s"`, needle, haystack), func() {
+		_, err := SE(&haystack, needle)
+		So(errors.Is(err, ErrEvaluation), ShouldBeTrue)
+		var perr *ParseError
+		So(errors.As(err, &perr), ShouldBeTrue)
+		if perr != nil {
+			So(perr.Component, ShouldEqual, ComponentReplacement)
+			if offset >= 0 {
+				So(perr.Offset, ShouldEqual, offset)
+			}
+		}
+	})
+}
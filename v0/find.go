@@ -0,0 +1,155 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"iter"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+)
+
+/*
+searchFrom is the variant of the regexp used to search from an offset, see search.
+It is compiled on first use, and shared by the copies of the RE the cache hands out.
+*/
+type searchFrom struct {
+	once  sync.Once
+	regex *regexp.Regexp
+}
+
+func (r *RE) fromRegex() *regexp.Regexp {
+	if r.from == nil {
+		r.from = &searchFrom{} // eg. a Pattern from FromRegexp
+	}
+	r.from.once.Do(func() {
+		r.from.regex = regexp.MustCompile(`\A(?s:.)(?s:.)*?(` + *r.n + `)`)
+	})
+	return r.from.regex
+}
+
+/*
+findAll finds up to n successive non-overlapping matches starting at or after the offset, all of them if n < 0, like regexp.Regexp.FindAllStringSubmatchIndex.
+*/
+func (r *RE) findAll(haystack string, from, n int) [][]int {
+	if r.anchor == nil && !r.w && from <= 0 {
+		return r.regex.FindAllStringSubmatchIndex(haystack, n)
+	}
+
+	if n == 0 {
+		return nil
+	}
+	var matches [][]int
+	for match := range r.each(haystack, from) {
+		matches = append(matches, match)
+		if len(matches) == n {
+			break
+		}
+	}
+	return matches
+}
+
+/*
+each yields the successive non-overlapping matches starting at or after the offset one at a time, so the search stops when the caller stops.
+With the w flag the matches overlap, the search restarts one character after the start of each match instead of at its end.
+\G matches at the offset, and then where the previous match ended.
+*/
+func (r *RE) each(haystack string, from int) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		if r.regex == nil {
+			return // tr/// matches nothing
+		}
+		from = max(from, 0)
+		prevEnd := -1
+		for pos := from; pos <= len(haystack); {
+			anchor := prevEnd
+			if anchor < 0 {
+				anchor = from
+			}
+			match := r.find(haystack, pos, anchor)
+			if match == nil {
+				return
+			}
+			accept := r.w || !(match[0] == match[1] && match[0] == prevEnd) // no empty match right after the previous match, unless they overlap anyway
+			if accept {
+				if !yield(match) {
+					return
+				}
+				prevEnd = match[1]
+			}
+			if match[1] > match[0] && !r.w {
+				pos = match[1]
+			} else if match[0] < len(haystack) {
+				_, size := utf8.DecodeRuneInString(haystack[match[0]:])
+				pos = match[0] + size
+			} else {
+				return
+			}
+		}
+	}
+}
+
+/*
+search finds the first match starting at or after the offset.
+The text before the offset is still visible to the assertions, so eg. \b and (?m:^) work like they would at that position of the whole haystack.
+*/
+func (r *RE) search(haystack string, from int) []int {
+	if from == 0 {
+		return r.regex.FindStringSubmatchIndex(haystack)
+	}
+	if from > len(haystack) {
+		return nil
+	}
+	_, size := utf8.DecodeLastRuneInString(haystack[:from])
+	return shiftMatch(r.fromRegex().FindStringSubmatchIndex(haystack[from-size:]), from-size)
+}
+
+/*
+find finds the first match starting at or after the offset.
+\G matches at the anchor, or at the start of the haystack if the anchor is negative.
+*/
+func (r *RE) find(haystack string, from, anchor int) []int {
+	if r.anchor != nil {
+		if anchor < 0 {
+			anchor = 0
+		}
+		return r.searchAnchored(haystack, from, anchor)
+	}
+	return r.search(haystack, from)
+}
+
+/*
+shiftMatch drops the wrapping group of a search variant and moves the indices by the offset the variant was run from.
+*/
+func shiftMatch(match []int, offset int) []int {
+	if match == nil {
+		return nil
+	}
+	match = match[2:]
+	for i := range match {
+		if match[i] >= 0 {
+			match[i] += offset
+		}
+	}
+	return match
+}
+
+/*
+submatches turns the indices of a match into the matched texts, "" for groups that did not participate.
+*/
+func submatches(haystack string, match []int) []string {
+	texts := make([]string, len(match)/2)
+	for i := range texts {
+		if match[2*i] >= 0 {
+			texts[i] = haystack[match[2*i]:match[2*i+1]]
+		}
+	}
+	return texts
+}
@@ -0,0 +1,64 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Example_overlapping() {
+	fmt.Println(Mr("GATATATC", `m/TAT/g`).Matches, Mr("GATATATC", `m/TAT/gw`).Matches)
+	fmt.Println(Mr("kalle", `m/(\w\w)/gw`).S[1:])
+	// Output:
+	// 1 2
+	// [ka al ll le]
+}
+
+func TestOverlapping(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("the w flag restarts one character after the start of each match", t, func() {
+		r := Mr("ATATAT", `m/ATA/gw`)
+		So(r.Matches, ShouldEqual, 2)
+		So(r.All()[0].Span, ShouldResemble, [2]int{0, 3})
+		So(r.All()[1].Span, ShouldResemble, [2]int{2, 5})
+		So(Mr("ATATAT", `m/ATA/w`).Matches, ShouldEqual, 1)
+		So(Mr("äöü", `m/(..)/gw`).S, ShouldResemble, []string{"", "äö", "öü"})
+	})
+	Convey("named groups", t, func() {
+		r := Mr("kalle", `m/(?<pair>\w\w)/gw`)
+		So(r.Z, ShouldResemble, map[string]string{"pair": "le"})
+		So(r.ZAll["pair"], ShouldResemble, []string{"ka", "al", "ll", "le"})
+	})
+	Convey("empty matches", t, func() {
+		var spans [][2]int
+		for _, m := range Mr("aab", `m/a*/gw`).All() {
+			spans = append(spans, m.Span)
+		}
+		So(spans, ShouldResemble, [][2]int{{0, 2}, {1, 2}, {2, 2}, {3, 3}})
+		So(Mr("aaa b", `m/aa/gw`).Matches, ShouldEqual, 2)
+	})
+	Convey("patterns and iterators", t, func() {
+		p := MustQr(`m/AA/w`)
+		So(p.Match("AAAA").Matches, ShouldEqual, 1)
+		So(p.MatchAll("AAAA").Matches, ShouldEqual, 3)
+		count := 0
+		for range p.All("AAAA") {
+			count++
+		}
+		So(count, ShouldEqual, 3)
+	})
+	Convey("only m// finds overlapping matches", t, func() {
+		runErrorTest(`s/a/b/gw`, ErrSyntax)
+		runErrorTest(`tr/a/b/w`, ErrSyntax)
+	})
+}
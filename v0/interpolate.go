@@ -0,0 +1,193 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"strings"
+)
+
+/*
+QrWith parses the needle like Qr, but first interpolates the Patterns in vars into the matching pattern, like Perl does with qr// objects
+
+	date := MustQr(`m/\d{4}-\d\d-\d\d/i`)
+	time := MustQr(`m/(?P<hour>\d\d):\d\d/`)
+	stamp := MustQrWith(`m/^ $date \s+ ${time} /x`, map[string]*Pattern{"date": date, "time": time})
+
+Each interpolated Pattern keeps its own i, m and s flags, and the x flag of the outer needle does not reach into it.
+Capture groups of the interpolated Patterns are numbered in the order they appear in the resulting pattern, and named groups are reachable through Z.
+Referring to a variable not in vars is an ErrSyntax.
+*/
+func QrWith(needle string, vars map[string]*Pattern) (*Pattern, error) {
+	return defaultSession.QrWith(needle, vars)
+}
+
+/*
+MustQrWith is the "must" flavour of QrWith, it panics with a *ParseError if the needle is invalid.
+*/
+func MustQrWith(needle string, vars map[string]*Pattern) *Pattern {
+	p, err := QrWith(needle, vars)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+/*
+String stringifies the Pattern like Perl stringifies qr// objects, eg. `(?^i:\d+)`.
+The result can be written into another needle and keeps its own flags there.
+*/
+func (p *Pattern) String() string {
+	return `(?^` + goFlags(*p.re.f) + `:` + p.re.body() + `)`
+}
+
+/*
+GoGroup returns the Pattern as a self-contained Go regexp group, eg. `(?i-ms:\d+)`, for use with the regexp package directly.
+*/
+func (p *Pattern) GoGroup() string {
+	return p.re.goGroup()
+}
+
+/*
+body is the matching pattern after preprocessing, without the flags prefix
+*/
+func (r *RE) body() string {
+	if r.anchor != nil {
+		return r.anchor.body
+	}
+	return (*r.n)[r.nPrefix:]
+}
+
+/*
+goGroup wraps the matching pattern into a group setting exactly the flags of the needle and clearing the rest.
+*/
+func (r *RE) goGroup() string {
+	return `(?` + goFlagsScoped(goFlags(*r.f)) + `:` + r.body() + `)`
+}
+
+/*
+goFlagsScoped turns the flags into a Go flags-setting, which also clears the flags not given, eg. "i" to "i-ms"
+*/
+func goFlagsScoped(flags string) string {
+	sb := strings.Builder{}
+	sb.WriteString(flags)
+	for _, c := range []byte("ims") {
+		if strings.IndexByte(flags, c) < 0 {
+			if sb.Len() == len(flags) {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+/*
+caretFlags translates the Perl (?^flags: or (?^flags) at offset i of the needle into the Go equivalent.
+end is the offset of the terminating ':' or ')'.
+*/
+func caretFlags(r *RE, i int) (translated string, end int, err error) {
+	for end = i + 3; end < len(r._orig); end++ {
+		switch c := r._orig[end]; c {
+		case ':', ')':
+			return `(?` + goFlagsScoped(r._orig[i+3:end]), end, nil
+		case 'i', 'm', 's':
+			continue
+		default:
+			return "", end, newParseError(r, ErrUnsupportedFeature, ComponentMatch, end, "flag '%c' in '(?^' is not supported", c)
+		}
+	}
+	return "", end, newParseError(r, ErrMissingTerminator, ComponentMatch, i, "unterminated '(?^'")
+}
+
+/*
+interpolate replaces the $name and ${name} variables in the matching pattern with the Go groups of the Patterns.
+It runs before flagHandler_x, so the x flag does not end the name of a variable, and returns the offsets of the groups for flagHandler_x to leave alone.
+*/
+func interpolate(r *RE, vars map[string]*Pattern) ([][2]int, error) {
+	regstr := *r.n
+	sb := strings.Builder{}
+	sb.Grow(len(regstr))
+	newPos := make([]int, 0, len(r.nPos))
+	var interpolated [][2]int
+
+	var inBracketedCharacterClass bool
+	for i := 0; i < len(regstr); i++ {
+		switch regstr[i] {
+		case '[':
+			inBracketedCharacterClass = true
+		case ']':
+			inBracketedCharacterClass = false
+		case '\\':
+			sb.WriteByte(regstr[i])
+			newPos = append(newPos, r.nPos[i])
+			i++ // Skip the escaping backslash and the character being escaped
+			sb.WriteByte(regstr[i])
+			newPos = append(newPos, r.nPos[i])
+			continue
+		case '$':
+			if inBracketedCharacterClass {
+				break
+			}
+			name, end := variableName(regstr, i)
+			if name == "" {
+				break
+			}
+			v, ok := vars[name]
+			if !ok {
+				return nil, newParseError(r, ErrSyntax, ComponentMatch, r.nPos[i], "undefined variable '%s'", name)
+			}
+			if v.re.anchor != nil {
+				return nil, newParseError(r, ErrUnsupportedFeature, ComponentMatch, r.nPos[i], "interpolating '%s' with \\G", name)
+			}
+			group := v.re.goGroup()
+			interpolated = append(interpolated, [2]int{sb.Len(), sb.Len() + len(group)})
+			sb.WriteString(group)
+			for j := 0; j < len(group); j++ {
+				newPos = append(newPos, r.nPos[i])
+			}
+			i = end - 1
+			continue
+		}
+		sb.WriteByte(regstr[i])
+		newPos = append(newPos, r.nPos[i])
+	}
+	*r.n = sb.String()
+	r.nPos = newPos
+	return interpolated, nil
+}
+
+/*
+variableName parses $name or ${name} at offset i, end is the offset after the variable.
+*/
+func variableName(regstr string, i int) (name string, end int) {
+	start := i + 1
+	braced := start < len(regstr) && regstr[start] == '{'
+	if braced {
+		start++
+	}
+	end = start
+	for ; end < len(regstr); end++ {
+		c := regstr[end]
+		if c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (end > start && '0' <= c && c <= '9') {
+			continue
+		}
+		break
+	}
+	if end == start {
+		return "", i + 1
+	}
+	if braced {
+		if end >= len(regstr) || regstr[end] != '}' {
+			return "", i + 1
+		}
+		return regstr[start:end], end + 1
+	}
+	return regstr[start:end], end
+}
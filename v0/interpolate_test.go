@@ -0,0 +1,61 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInterpolate(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("stringification", t, func() {
+		So(MustQr(`m/\d+/`).String(), ShouldEqual, `(?^:\d+)`)
+		So(MustQr(`m/ \d+ # digits
+		/xi`).String(), ShouldEqual, `(?^i:\d+)`)
+		So(MustQr(`m/\d+/ism`).GoGroup(), ShouldEqual, `(?ims:\d+)`)
+		So(MustQr(`m/a b/s`).GoGroup(), ShouldEqual, `(?s-im:a b)`)
+		So(MustQr(`m/a/`).GoGroup(), ShouldEqual, `(?-ims:a)`)
+	})
+	Convey("stringified patterns keep their flags inside other needles", t, func() {
+		word := MustQr(`m/kalle/i`)
+		So(M("KALLE ankka", `m/`+word.String()+` ankka/`), ShouldBeTrue)
+		So(M("KALLE ANKKA", `m/`+word.String()+` ankka/`), ShouldBeFalse)
+		So(M("kalle ANKKA", `m/(?^:kalle) ankka/i`), ShouldBeTrue)
+		So(M("KALLE ankka", `m/(?^:kalle) ankka/i`), ShouldBeFalse)
+		_, err := Compile(`m/(?^x:kalle)/`)
+		So(errors.Is(err, ErrUnsupportedFeature), ShouldBeTrue)
+	})
+	Convey("interpolation", t, func() {
+		date := MustQr(`m/(?P<year>\d{4})-(\d\d)-(\d\d)/`)
+		time := MustQr(`m/(?P<hour>\d\d) : (\d\d) # x is scoped to the inner pattern
+		/x`)
+		name := MustQr(`m/kalle ankka/i`)
+		stamp := MustQrWith(`m/^ $name \s+ ${date} T $time $/x`, map[string]*Pattern{"date": date, "time": time, "name": name})
+		r := stamp.Match("Kalle Ankka 2021-12-31T23:59")
+		So(r.Matches, ShouldEqual, 1)
+		So(r.S, ShouldResemble, []string{"", "2021", "12", "31", "23", "59"})
+		So(r.Z, ShouldResemble, map[string]string{"year": "2021", "hour": "23"})
+		So(stamp.Match("Kalle Ankka 2021-12-31t23:59").Matches, ShouldEqual, 0)
+
+		r = MustQrWith(`m/$date T $time/x`, map[string]*Pattern{"date": date, "time": time}).Match("2021-12-31T23:59")
+		So(r.Matches, ShouldEqual, 1)
+		So(MustQrWith("m/ $name\t# the name $name\n/x", map[string]*Pattern{"name": MustQr(`m/kalle # ankka/`)}).Match("kalle # ankka").Matches, ShouldEqual, 1)
+
+		So(MustQrWith(`m/[$x]\$x/`, map[string]*Pattern{}).Match("x$x").Matches, ShouldEqual, 1)
+		_, err := QrWith(`m/a $undefined/`, nil)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		So(err.(*ParseError).Offset, ShouldEqual, 4)
+		_, err = QrWith("m/ a\n  $undefined /x", map[string]*Pattern{})
+		So(err.(*ParseError).Offset, ShouldEqual, 7)
+	})
+}
@@ -0,0 +1,169 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"iter"
+	"strings"
+)
+
+/*
+All iterates over every match of the needle in the haystack, with or without the g flag. It panics with a *ParseError if the needle is invalid.
+
+	for m := range All(log, `m/^(?<user>\w+): ERROR/m`) {
+		fmt.Println(m.Named["user"], m.Span)
+	}
+
+The haystack is searched lazily, breaking out of the loop stops the search. The iterators do not touch R0.
+A m?...? needle is not limited to one match by the iterators, they search every time, see Session.Reset.
+*/
+func All(haystack, needle string) iter.Seq[Match] {
+	return defaultSession.All(haystack, needle)
+}
+
+/*
+AllNamed iterates over the named groups of every match, like Match.Named, with the number of the match from 0.
+*/
+func AllNamed(haystack, needle string) iter.Seq2[int, map[string]string] {
+	return defaultSession.AllNamed(haystack, needle)
+}
+
+/*
+Grep iterates over the lines of the haystack which match the needle, with their 1-based line numbers, like Perl's grep over the lines of a file.
+The lines are matched one at a time without their newline, so ^ and $ match at their start and end.
+*/
+func Grep(haystack, needle string) iter.Seq2[int, string] {
+	return defaultSession.Grep(haystack, needle)
+}
+
+/*
+Substitutions iterates over every match of a s/// needle with what s/// substitutes it with, without substituting. It panics with a *ParseError if the needle is invalid,
+or if evaluating the replacement expression of s///e fails for a match.
+
+	for m, replacement := range Substitutions(src, `s/colour/color/gik`) {
+		fmt.Printf("%d: %q => %q\n", m.Span[0], m.Match(), replacement)
+	}
+*/
+func Substitutions(haystack, needle string) iter.Seq2[Match, string] {
+	return defaultSession.Substitutions(haystack, needle)
+}
+
+func (self *Session) All(haystack, needle string) iter.Seq[Match] {
+	return self.regexParser(&needle).matches(haystack)
+}
+
+func (self *Session) AllNamed(haystack, needle string) iter.Seq2[int, map[string]string] {
+	return self.regexParser(&needle).named(haystack)
+}
+
+func (self *Session) Grep(haystack, needle string) iter.Seq2[int, string] {
+	return self.regexParser(&needle).grep(haystack)
+}
+
+func (self *Session) Substitutions(haystack, needle string) iter.Seq2[Match, string] {
+	r := self.regexParser(&needle)
+	if r.mode != 's' {
+		panic(newParseError(r, ErrSyntax, ComponentMode, 0, "not a s/// needle"))
+	}
+	return r.substitutions(haystack)
+}
+
+/*
+All is the package level All with the Pattern.
+*/
+func (p *Pattern) All(haystack string) iter.Seq[Match] {
+	return p.re.matches(haystack)
+}
+
+/*
+AllNamed is the package level AllNamed with the Pattern.
+*/
+func (p *Pattern) AllNamed(haystack string) iter.Seq2[int, map[string]string] {
+	return p.re.named(haystack)
+}
+
+/*
+Grep is the package level Grep with the Pattern.
+*/
+func (p *Pattern) Grep(haystack string) iter.Seq2[int, string] {
+	return p.re.grep(haystack)
+}
+
+/*
+Substitutions is the package level Substitutions with the Pattern, see WithReplacement for substituting with a match Pattern.
+*/
+func (p *Pattern) Substitutions(haystack string) iter.Seq2[Match, string] {
+	return p.re.substitutions(haystack)
+}
+
+/*
+matches is the iterator of All, numbering the matches like Match.Index
+*/
+func (r *RE) matches(haystack string) iter.Seq[Match] {
+	return func(yield func(Match) bool) {
+		i := 0
+		for match := range r.each(haystack, 0) {
+			if !yield(newMatch(r, haystack, match, i)) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+/*
+named is the iterator of AllNamed
+*/
+func (r *RE) named(haystack string) iter.Seq2[int, map[string]string] {
+	return func(yield func(int, map[string]string) bool) {
+		for m := range r.matches(haystack) {
+			if !yield(m.Index, m.Named) {
+				return
+			}
+		}
+	}
+}
+
+/*
+grep is the iterator of Grep
+*/
+func (r *RE) grep(haystack string) iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		if r.regex == nil {
+			return // tr/// matches nothing
+		}
+		rest := haystack
+		for n := 1; rest != ""; n++ {
+			var line string
+			line, rest, _ = strings.Cut(rest, "\n")
+			if r.find(line, 0, 0) != nil && !yield(n, line) {
+				return
+			}
+		}
+	}
+}
+
+/*
+substitutions is the iterator of Substitutions, reusing the buffer of the replacement between the matches
+*/
+func (r *RE) substitutions(haystack string) iter.Seq2[Match, string] {
+	return func(yield func(Match, string) bool) {
+		var replacement []byte
+		for m := range r.matches(haystack) {
+			var err error
+			if replacement, err = r.replace(replacement[:0], haystack, m.Offsets, m.Index, nil); err != nil {
+				panic(err)
+			}
+			if !yield(m, string(replacement)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleAll() {
+	for m := range All("kalle: ok\nankka: ERROR\nmikki: ERROR", `m/^(?<user>\w+): ERROR$/m`) {
+		fmt.Println(m.Index, m.Named["user"], m.Span)
+	}
+	// Output:
+	// 0 ankka [10 22]
+	// 1 mikki [23 35]
+}
+
+func ExampleGrep() {
+	for n, line := range Grep("package re\n\n// TODO: tests\nfunc f() {} // TODO", `m/TODO/`) {
+		fmt.Println(n, line)
+	}
+	// Output:
+	// 3 // TODO: tests
+	// 4 func f() {} // TODO
+}
+
+func TestIterators(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("every match, with or without the g flag", t, func() {
+		var groups [][]string
+		for m := range All("a1 b2 c3", `m/(\w)(\d)/`) {
+			groups = append(groups, m.Groups)
+		}
+		So(groups, ShouldResemble, [][]string{{"a1", "a", "1"}, {"b2", "b", "2"}, {"c3", "c", "3"}})
+
+		var spans [][2]int
+		for m := range MustQr(`m/x*/`).All("ab") {
+			spans = append(spans, m.Span)
+		}
+		So(spans, ShouldResemble, [][2]int{{0, 0}, {1, 1}, {2, 2}})
+
+		seq := All("aa bb ab", `m/aa|bb/`)
+		for range 2 {
+			var matches []string
+			for m := range seq {
+				matches = append(matches, m.Match())
+			}
+			So(matches, ShouldResemble, []string{"aa", "bb"})
+		}
+	})
+	Convey("\\G keeps the matches adjacent", t, func() {
+		count := 0
+		for range All("1 2 3x4", `m/\G\s*\d/`) {
+			count++
+		}
+		So(count, ShouldEqual, 3)
+	})
+	Convey("named groups of each match", t, func() {
+		var named []map[string]string
+		var indices []int
+		for i, z := range AllNamed("2021-03-04 2022-12", `m/(?<y>\d{4})-(?<m>\d\d)(?:-(?<d>\d\d))?/`) {
+			indices = append(indices, i)
+			named = append(named, z)
+		}
+		So(indices, ShouldResemble, []int{0, 1})
+		So(named, ShouldResemble, []map[string]string{{"y": "2021", "m": "03", "d": "04"}, {"y": "2022", "m": "12"}})
+	})
+	Convey("lines", t, func() {
+		var lines []string
+		for n, line := range MustQr(`m/^\s*$/`).Grep("a\n\n  \nb\n") {
+			lines = append(lines, fmt.Sprint(n, ":", line))
+		}
+		So(lines, ShouldResemble, []string{"2:", "3:  "})
+		for range Grep("", `m/^/`) {
+			So("an empty haystack has no lines", ShouldBeEmpty)
+		}
+		for range Grep("kalle", `tr/a/b/`) {
+			So("tr/// matches no lines", ShouldBeEmpty)
+		}
+	})
+	Convey("substitution steps", t, func() {
+		var steps []string
+		for m, replacement := range Substitutions("Colour, colour, COLOUR", `s/colour/color/ik`) {
+			steps = append(steps, m.Match()+" => "+replacement)
+		}
+		So(steps, ShouldResemble, []string{"Colour => Color", "colour => color", "COLOUR => COLOR"})
+
+		steps = nil
+		for m, replacement := range MustQr(`m/(\d+)/`).WithReplacement(`<$1>`).Substitutions("1 22") {
+			steps = append(steps, fmt.Sprint(m.Span, replacement))
+		}
+		So(steps, ShouldResemble, []string{"[0 1]<1>", "[2 4]<22>"})
+
+		So(func() { Substitutions("a", `m/a/`) }, ShouldPanic)
+		So(func() {
+			for range Substitutions("1 0", `s/(\d+)/1 \/ $1/e`) {
+			}
+		}, ShouldPanic)
+	})
+	Convey("breaking out of the loop stops the search", t, func() {
+		// The third replacement divides by zero
+		So(func() {
+			for m, replacement := range Substitutions("1 2 0 4", `s/(\d+)/12 \/ $1/e`) {
+				So(replacement, ShouldEqual, fmt.Sprint(12/(m.Index+1)))
+				if m.Index == 1 {
+					break
+				}
+			}
+		}, ShouldNotPanic)
+	})
+	Convey("the iterators do not touch R0", t, func() {
+		r := Mr("kalle", `m/k/`)
+		for range All("ankka", `m/a/`) {
+		}
+		So(R0, ShouldEqual, r)
+		So(func() { All("a", `m/(/`) }, ShouldPanic)
+	})
+}
@@ -0,0 +1,277 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import "sync"
+
+/*
+Match is one match of the matching pattern, with the captures of that match only. RE.All returns one for each match.
+
+	r := Mr(log, `m/^(?<user>\w+): (?:(?<error>ERROR)|\w+)/gm`)
+	for _, m := range r.All() {
+		if _, failed := m.Named["error"]; failed {
+			fmt.Println(m.Named["user"], m.Span)
+		}
+	}
+
+Unlike S, Groups starts with the whole match, like regexp.Regexp.FindStringSubmatch.
+Named holds the named groups which participated in the match, even if they captured an empty string, so optional groups can be told apart.
+*/
+type Match struct {
+	Index     int               // the number of the match from 0
+	Groups    []string          // $&, $1, ..., $n, "" for groups which did not participate in the match
+	Named     map[string]string // %+, the named groups which participated in the match
+	Span      [2]int            // the start and end offsets of $& in the haystack
+	Offsets   []int             // the start and end offsets of $&, $1, ..., $n in the haystack, -1 for groups which did not participate
+	Positions []Position        // the line and column of each of Offsets, filled by RE.Locate
+
+	haystack string         // the haystack as it was before a substitution
+	groups   []CaptureGroup // the capture groups of the matching pattern
+}
+
+/*
+newMatch is the Match of the match indices, numbered like S.
+*/
+func newMatch(r *RE, haystack string, match []int, index int) Match {
+	m := Match{
+		Index:   index,
+		Groups:  submatches(haystack, match),
+		Span:    [2]int{match[0], match[1]},
+		Offsets: append([]int(nil), match...),
+
+		haystack: haystack,
+		groups:   r.groups,
+	}
+	if r.nCaptures {
+		m.Named = make(map[string]string)
+		for _, g := range r.groups {
+			if g.Name != "" && match[2*g.Index] >= 0 {
+				m.Named[g.Name] = m.Groups[g.Index]
+			}
+		}
+	}
+	return m
+}
+
+/*
+matchList is All of a result, built once on first use, even when goroutines sharing the RE ask for it at the same time
+*/
+type matchList struct {
+	once    sync.Once
+	matches []Match
+}
+
+/*
+matchResults keeps the matches for All, which is only built when asked for
+*/
+func matchResults(r *RE, haystack string, matches [][]int) {
+	r.haystack, r.indices, r.all = haystack, matches, &matchList{}
+}
+
+/*
+All returns each match with its own captures, see Match. The list is built from the offsets of the matches on the first call.
+*/
+func (r *RE) All() []Match {
+	if r.all == nil {
+		return nil
+	}
+	r.all.once.Do(func() {
+		if len(r.indices) == 0 {
+			return
+		}
+		r.all.matches = make([]Match, len(r.indices))
+		for i, match := range r.indices {
+			r.all.matches[i] = newMatch(r, r.haystack, match, r.Index+i) // Index is 0 but in the RE of a single match given to a callback
+		}
+	})
+	return r.all.matches
+}
+
+/*
+Start is the offset in the haystack where the group starts, like Perl's $-[n], -1 if it did not participate in the match. Group 0 is the whole match.
+*/
+func (m *Match) Start(n int) int {
+	if n < 0 || 2*n+1 >= len(m.Offsets) {
+		return -1
+	}
+	return m.Offsets[2*n]
+}
+
+/*
+End is the offset in the haystack where the group ends, like Perl's $+[n], -1 if it did not participate in the match. Group 0 is the whole match.
+*/
+func (m *Match) End(n int) int {
+	if n < 0 || 2*n+1 >= len(m.Offsets) {
+		return -1
+	}
+	return m.Offsets[2*n+1]
+}
+
+/*
+StartName is Start of the named group, -1 if there is no such group or it did not participate in the match.
+*/
+func (m *Match) StartName(name string) int {
+	return m.Start(m.named(name))
+}
+
+/*
+EndName is End of the named group, -1 if there is no such group or it did not participate in the match.
+*/
+func (m *Match) EndName(name string) int {
+	return m.End(m.named(name))
+}
+
+/*
+named is the number of the leftmost group with the name which participated in the match, -1 if none
+*/
+func (m *Match) named(name string) int {
+	for _, g := range m.groups {
+		if g.Name == name && m.Start(g.Index) >= 0 {
+			return g.Index
+		}
+	}
+	return -1
+}
+
+/*
+Prematch is the text before the match, like Perl's $`
+*/
+func (m *Match) Prematch() string {
+	if m.Offsets == nil {
+		return ""
+	}
+	return m.haystack[:m.Offsets[0]]
+}
+
+/*
+Match is the text matched, like Perl's $&
+*/
+func (m *Match) Match() string {
+	if m.Offsets == nil {
+		return ""
+	}
+	return m.haystack[m.Offsets[0]:m.Offsets[1]]
+}
+
+/*
+Postmatch is the text after the match, like Perl's $'
+*/
+func (m *Match) Postmatch() string {
+	if m.Offsets == nil {
+		return ""
+	}
+	return m.haystack[m.Offsets[1]:]
+}
+
+/*
+LastParen is the text of the highest-numbered group which participated in the match, like Perl's $+
+*/
+func (m *Match) LastParen() string {
+	for n := len(m.Offsets)/2 - 1; n > 0; n-- {
+		if m.Start(n) >= 0 {
+			return m.haystack[m.Start(n):m.End(n)]
+		}
+	}
+	return ""
+}
+
+/*
+LastClosed is the text of the group which closed last, like Perl's $^N.
+That is the group ending furthest in the haystack, and of those the outermost, or the last one of siblings.
+*/
+func (m *Match) LastClosed() string {
+	last, depth := -1, 0
+	for _, g := range m.groups {
+		if m.Start(g.Index) < 0 {
+			continue
+		}
+		if last < 0 || m.End(g.Index) > m.End(last) || (m.End(g.Index) == m.End(last) && g.Depth <= depth) {
+			last, depth = g.Index, g.Depth
+		}
+	}
+	if last < 0 {
+		return ""
+	}
+	return m.haystack[m.Start(last):m.End(last)]
+}
+
+/*
+lastMatch is the match the accessors of RE refer to, the last one like in Perl
+*/
+func (r *RE) lastMatch() *Match {
+	if len(r.indices) == 0 {
+		return &Match{}
+	}
+	last := newMatch(r, r.haystack, r.indices[len(r.indices)-1], r.Index+len(r.indices)-1)
+	return &last
+}
+
+/*
+Start is Match.Start of the last match, see All for the other matches.
+*/
+func (r *RE) Start(n int) int {
+	return r.lastMatch().Start(n)
+}
+
+/*
+End is Match.End of the last match.
+*/
+func (r *RE) End(n int) int {
+	return r.lastMatch().End(n)
+}
+
+/*
+StartName is Match.StartName of the last match.
+*/
+func (r *RE) StartName(name string) int {
+	return r.lastMatch().StartName(name)
+}
+
+/*
+EndName is Match.EndName of the last match.
+*/
+func (r *RE) EndName(name string) int {
+	return r.lastMatch().EndName(name)
+}
+
+/*
+Prematch is Match.Prematch of the last match.
+*/
+func (r *RE) Prematch() string {
+	return r.lastMatch().Prematch()
+}
+
+/*
+Match is Match.Match of the last match.
+*/
+func (r *RE) Match() string {
+	return r.lastMatch().Match()
+}
+
+/*
+Postmatch is Match.Postmatch of the last match.
+*/
+func (r *RE) Postmatch() string {
+	return r.lastMatch().Postmatch()
+}
+
+/*
+LastParen is Match.LastParen of the last match.
+*/
+func (r *RE) LastParen() string {
+	return r.lastMatch().LastParen()
+}
+
+/*
+LastClosed is Match.LastClosed of the last match.
+*/
+func (r *RE) LastClosed() string {
+	return r.lastMatch().LastClosed()
+}
@@ -0,0 +1,187 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleMatch() {
+	r := Mr("kalle: ok\nankka: ERROR\nmikki: ok", `m/^(?<user>\w+): (?:(?<error>ERROR)|\w+)$/gm`)
+	for _, m := range r.All() {
+		if _, failed := m.Named["error"]; failed {
+			fmt.Println(m.Index, m.Named["user"], m.Span)
+		}
+	}
+	// Output: 1 ankka [10 22]
+}
+
+func TestMatch(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("each match on its own", t, func() {
+		r := Mr("2021-03-04 and 2022-12", `m/(?<y>\d{4})-(\d\d)(?:-(?<d>\d\d))?/g`)
+		So(len(r.All()), ShouldEqual, 2)
+		runMatchResultTest(r.All()[0], 0, []string{"2021-03-04", "2021", "03", "04"}, map[string]string{"y": "2021", "d": "04"}, []int{0, 10, 0, 4, 5, 7, 8, 10})
+		runMatchResultTest(r.All()[1], 1, []string{"2022-12", "2022", "12", ""}, map[string]string{"y": "2022"}, []int{15, 22, 15, 19, 20, 22, -1, -1})
+		So(r.S, ShouldResemble, []string{"", "2021", "03", "04", "2022", "12", ""})
+		So(r.Z, ShouldResemble, map[string]string{"y": "2022", "d": "04"})
+	})
+	Convey("empty captures participate", t, func() {
+		r := Mr("a=", `m/(?<k>\w)=(?<v>\w*)/`)
+		So(r.All()[0].Named, ShouldResemble, map[string]string{"k": "a", "v": ""})
+		So(r.Z, ShouldResemble, map[string]string{"k": "a"})
+	})
+	Convey("without groups", t, func() {
+		r := Mr("kalle ankka", `m/a/g`)
+		So(len(r.All()), ShouldEqual, 3)
+		So(r.All()[2].Groups, ShouldResemble, []string{"a"})
+		So(r.All()[2].Named, ShouldBeNil)
+		So(Mr("kalle", `m/x/`).All(), ShouldBeNil)
+	})
+	Convey("the ISO8601 parser", t, func() {
+		r := Mr("kalle: 2021-12-31\npaavo: 2020-10-31T21:39", `m/^(?P<username>\w+):\s+(?P<year>\d{4})-\d\d-\d\d(?:T(?P<time>[\d:]+))?$/gm`)
+		So(r.All()[1].Named["username"], ShouldEqual, "paavo")
+		So(r.All()[1].Groups[1], ShouldEqual, r.S[4])
+		_, hasTime := r.All()[0].Named["time"]
+		So(hasTime, ShouldBeFalse)
+		So(r.All()[1].Named["time"], ShouldEqual, "21:39")
+	})
+	Convey("match lists, substitutions and callbacks", t, func() {
+		r := Mr("aa bb", `m/(\w)\w/g`)
+		So(len(r.All()), ShouldEqual, 2)
+		So(r.All()[1].Groups, ShouldResemble, []string{"bb", "b"})
+		So(r.All()[1].Offsets, ShouldResemble, []int{3, 5, 3, 4})
+
+		str := "1 2 3"
+		r = SrWith(&str, `s/(\d)/x/`, SubstOptions{Nth: 2})
+		So(len(r.All()), ShouldEqual, 1)
+		So(r.All()[0].Groups, ShouldResemble, []string{"2", "2"})
+
+		Se(&str, `s/(\w)//eg`, func(m *RE) string {
+			So(len(m.All()), ShouldEqual, 1)
+			runMatchResultTest(m.All()[0], m.Index, []string{m.S[1], m.S[1]}, nil, m.Offsets)
+			return ""
+		})
+	})
+}
+
+func TestMatchAccessors(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("offsets like @- and @+", t, func() {
+		r := Mr("kalle ankka", `m/(?<v>[aeiou])(x)?(\w)/`)
+		So(r.Start(0), ShouldEqual, 1)
+		So(r.End(0), ShouldEqual, 3)
+		So(r.Start(1), ShouldEqual, 1)
+		So(r.End(3), ShouldEqual, 3)
+		So(r.Start(2), ShouldEqual, -1)
+		So(r.End(2), ShouldEqual, -1)
+		So(r.Start(4), ShouldEqual, -1)
+		So(r.Start(-1), ShouldEqual, -1)
+		So(r.StartName("v"), ShouldEqual, 1)
+		So(r.EndName("v"), ShouldEqual, 2)
+		So(r.StartName("missing"), ShouldEqual, -1)
+	})
+	Convey("$`, $& and $'", t, func() {
+		r := Mr("kalle ankka", `m/ll/`)
+		So(r.Prematch(), ShouldEqual, "ka")
+		So(r.Match(), ShouldEqual, "ll")
+		So(r.Postmatch(), ShouldEqual, "e ankka")
+	})
+	Convey("$+ and $^N", t, func() {
+		r := Mr("Version: 1.2", `m/Version: (.*)|Revision: (.*)/`)
+		So(r.LastParen(), ShouldEqual, "1.2")
+		r = Mr("ab", `m/((a)(b))/`)
+		So(r.LastParen(), ShouldEqual, "b")
+		So(r.LastClosed(), ShouldEqual, "ab")
+		r = Mr("ab", `m/(a)(b)()/`)
+		So(r.LastClosed(), ShouldEqual, "")
+		So(r.End(3), ShouldEqual, 2)
+		r = Mr("ab", `m/(a)(b)(x)?/`)
+		So(r.LastClosed(), ShouldEqual, "b")
+		So(Mr("ab", `m/ab/`).LastClosed(), ShouldEqual, "")
+	})
+	Convey("under /g the RE refers to the last match, All to each", t, func() {
+		r := Mr("a1 b2 c3", `m/(\w)(\d)/g`)
+		So(r.Start(0), ShouldEqual, 6)
+		So(r.Match(), ShouldEqual, "c3")
+		So(r.Prematch(), ShouldEqual, "a1 b2 ")
+		So(r.All()[1].Start(2), ShouldEqual, 4)
+		So(r.All()[1].Match(), ShouldEqual, "b2")
+		So(r.All()[1].Postmatch(), ShouldEqual, " c3")
+		So(r.All()[0].LastParen(), ShouldEqual, "1")
+	})
+	Convey("substitutions refer to the haystack before substituting", t, func() {
+		str := "kalle ankka"
+		r := Sr(&str, `s/(?<first>\w+) (\w+)/$2 $1/`)
+		So(str, ShouldEqual, "ankka kalle")
+		So(r.Match(), ShouldEqual, "kalle ankka")
+		So(r.StartName("first"), ShouldEqual, 0)
+		So(r.Start(2), ShouldEqual, 6)
+		Se(&str, `s/(k)//eg`, func(m *RE) string {
+			So(m.Start(1), ShouldEqual, m.Offsets[2])
+			So(m.Prematch(), ShouldEqual, str[:m.Offsets[0]])
+			return "K"
+		})
+	})
+	Convey("goroutines sharing the result", t, func() {
+		r := Mr("a1 b2 c3", `m/(\w)(\d)/g`)
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = r.All()[2].Groups
+			}()
+		}
+		wg.Wait()
+		So(r.All()[2].Groups, ShouldResemble, []string{"c3", "c", "3"})
+	})
+	Convey("without a match", t, func() {
+		r := Mr("kalle", `m/x/`)
+		So(r.Start(0), ShouldEqual, -1)
+		So(r.Match(), ShouldEqual, "")
+		So(r.Prematch(), ShouldEqual, "")
+		So(r.LastParen(), ShouldEqual, "")
+		So(r.LastClosed(), ShouldEqual, "")
+	})
+}
+
+func runMatchResultTest(m Match, index int, groups []string, named map[string]string, offsets []int) {
+	So(m.Index, ShouldEqual, index)
+	So(m.Groups, ShouldResemble, groups)
+	So(m.Named, ShouldResemble, named)
+	So(m.Span, ShouldResemble, [2]int{offsets[0], offsets[1]})
+	So(m.Offsets, ShouldResemble, offsets)
+}
+
+/*
+The matches are only turned into All when asked for, so m//g costs the same as before All existed.
+*/
+func BenchmarkMatchGlobal(b *testing.B) {
+	sess := NewSession()
+	for i := 0; i < b.N; i++ {
+		if !sess.M("kalle=1 ankka=2 paavo=3", `m/(\w+)=(\d)/g`) {
+			b.Errorf("BenchmarkMatchGlobal regexp doesnt match?")
+		}
+	}
+}
+func BenchmarkMatchGlobalAll(b *testing.B) {
+	sess := NewSession()
+	for i := 0; i < b.N; i++ {
+		sess.M("kalle=1 ankka=2 paavo=3", `m/(\w+)=(\d)/g`)
+		if len(sess.Last.All()) != 3 {
+			b.Errorf("BenchmarkMatchGlobalAll regexp doesnt match?")
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import "unicode/utf8"
+
+/*
+Matcher walks the matches of a haystack one at a time, like m//g in Perl's scalar context walks them with pos().
+
+	mt := NewMatcher("a=1 b=2")
+	for mt.M(`m/(\w+)=(\d+)/g`) {
+		fmt.Println(mt.Last.S[1], mt.Last.S[2])
+	}
+
+With the g flag the search starts at Pos, which moves to the end of each match, or with the w flag one character past its start, so the matches overlap like with Mr. A failing match resets Pos to 0, unless the needle has the c flag.
+Without the g flag the search starts from the beginning of the haystack and Pos is left alone, so such a needle in a loop never stops, just like in Perl.
+Different needles can take turns on the same haystack, each continuing where the previous one stopped:
+
+	mt := NewMatcher(`name="Kalle Ankka" age=42`)
+	for mt.M(`m/(\w+)=/gc`) {
+		key := mt.Last.S[1]
+		if mt.M(`m/"([^"]*)"|(\S+)/gc`) {
+			fmt.Println(key, mt.Last.S[1]+mt.Last.S[2])
+		}
+	}
+
+Each needle searches forward from Pos, like in Perl the match may start anywhere after it. \G anchors the match at Pos, see the lexer example.
+A Matcher does not keep the match-once state of m?...?, such a needle walks the matches like m//.
+*/
+type Matcher struct {
+	Last *RE // the result of the latest successful match, with the captures of that match only
+
+	session  *Session
+	haystack string
+	r        *RE // the needle of the latest M
+	pos      int
+	prevEnd  int // where the previous match ended, or started with the w flag, -1 if the position was set since, no empty match is accepted there
+	count    int // how many matches since the position was reset, the Index of the next match
+}
+
+/*
+NewMatcher binds a Matcher to the haystack, with the position at its beginning.
+*/
+func NewMatcher(haystack string) *Matcher {
+	return defaultSession.NewMatcher(haystack)
+}
+
+func (self *Session) NewMatcher(haystack string) *Matcher {
+	return &Matcher{session: self, haystack: haystack, prevEnd: -1}
+}
+
+/*
+M finds the next match of the needle, which also becomes the needle of Next. It panics with a *ParseError if the needle is invalid.
+*/
+func (mt *Matcher) M(needle string) bool {
+	r := mt.session.regexParser(&needle)
+	if r.mode != 'm' {
+		panic(newParseError(r, ErrSyntax, ComponentMode, 0, "not a m// needle"))
+	}
+	mt.r = r
+	return mt.Next()
+}
+
+/*
+ME is M, but returns an error instead of panicking on an invalid needle.
+*/
+func (mt *Matcher) ME(needle string) (bool, error) {
+	r, err := mt.session.regexParserE(&needle)
+	if err != nil {
+		return false, err
+	}
+	if r.mode != 'm' {
+		return false, newParseError(r, ErrSyntax, ComponentMode, 0, "not a m// needle")
+	}
+	mt.r = r
+	return mt.Next(), nil
+}
+
+/*
+Next finds the next match of the needle of the latest M, false if there is none or M was not called yet.
+*/
+func (mt *Matcher) Next() bool {
+	if mt.r == nil {
+		return false
+	}
+	global := mt.r.limit() < 0
+	from := 0
+	if global {
+		from = mt.pos
+	}
+	for from <= len(mt.haystack) {
+		match := mt.r.find(mt.haystack, from, mt.pos)
+		if match == nil {
+			break
+		}
+		again := match[0] == match[1] && match[0] == mt.prevEnd // no empty match right after the previous match, like with m//g
+		if mt.r.w {
+			again = match[0] == mt.prevEnd // the overlapping matches start one character apart, only the one at the end can come again
+		}
+		if global && again {
+			if match[0] >= len(mt.haystack) {
+				break
+			}
+			from = mt.afterRune(match[0])
+			continue
+		}
+
+		mt.Last = matchRE(mt.r, mt.haystack, match, mt.count)
+		mt.session.setLast(mt.Last)
+		if global && mt.r.w {
+			mt.pos, mt.prevEnd = min(mt.afterRune(match[0]), len(mt.haystack)), match[0]
+			mt.count++
+		} else if global {
+			mt.pos, mt.prevEnd = match[1], match[1]
+			mt.count++
+		}
+		return true
+	}
+	if global && !mt.r.c {
+		mt.Reset()
+	}
+	return false
+}
+
+/*
+afterRune is the offset of the character after the one at offset i, past the end of the haystack at its end
+*/
+func (mt *Matcher) afterRune(i int) int {
+	_, size := utf8.DecodeRuneInString(mt.haystack[i:])
+	return i + max(size, 1)
+}
+
+/*
+Pos is the offset in the haystack where the next m//g starts searching, like Perl's pos()
+*/
+func (mt *Matcher) Pos() int {
+	return mt.pos
+}
+
+/*
+SetPos moves the position, clamped to the haystack. The next match may be empty at the new position.
+*/
+func (mt *Matcher) SetPos(n int) {
+	if n < 0 {
+		n = 0
+	} else if n > len(mt.haystack) {
+		n = len(mt.haystack)
+	}
+	mt.pos, mt.prevEnd = n, -1
+}
+
+/*
+Reset moves the position back to the beginning of the haystack, like assigning undef to pos() in Perl.
+*/
+func (mt *Matcher) Reset() {
+	mt.pos, mt.prevEnd, mt.count = 0, -1, 0
+}
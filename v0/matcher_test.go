@@ -0,0 +1,146 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleMatcher() {
+	mt := NewMatcher(`name="Kalle Ankka" age=42`)
+	for mt.M(`m/(\w+)=/gc`) {
+		key := mt.Last.S[1]
+		if mt.M(`m/"([^"]*)"|(\S+)/gc`) {
+			fmt.Printf("%s: %s%s\n", key, mt.Last.S[1], mt.Last.S[2])
+		}
+	}
+	// Output:
+	// name: Kalle Ankka
+	// age: 42
+}
+
+func TestMatcher(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("one match at a time", t, func() {
+		mt := NewMatcher("a=1 b=2")
+		So(mt.M(`m/(\w+)=(\d+)/g`), ShouldBeTrue)
+		So(mt.Last.S, ShouldResemble, []string{"", "a", "1"})
+		So(mt.Last.Index, ShouldEqual, 0)
+		So(mt.Pos(), ShouldEqual, 3)
+		So(mt.Next(), ShouldBeTrue)
+		So(mt.Last.S, ShouldResemble, []string{"", "b", "2"})
+		So(mt.Last.Index, ShouldEqual, 1)
+		So(mt.Last.Prematch(), ShouldEqual, "a=1 ")
+		So(mt.Pos(), ShouldEqual, 7)
+		So(mt.Next(), ShouldBeFalse)
+		So(mt.Pos(), ShouldEqual, 0)
+		So(mt.Last.S[1], ShouldEqual, "b")
+		So(mt.Next(), ShouldBeTrue)
+		So(mt.Last.Index, ShouldEqual, 0)
+	})
+	Convey("the c flag keeps the position on failure", t, func() {
+		mt := NewMatcher("aab")
+		So(mt.M(`m/a/gc`), ShouldBeTrue)
+		So(mt.M(`m/a/gc`), ShouldBeTrue)
+		So(mt.M(`m/a/gc`), ShouldBeFalse)
+		So(mt.Pos(), ShouldEqual, 2)
+		So(mt.M(`m/b/g`), ShouldBeTrue)
+		So(mt.Pos(), ShouldEqual, 3)
+		So(mt.M(`m/b/g`), ShouldBeFalse)
+		So(mt.Pos(), ShouldEqual, 0)
+	})
+	Convey("without the g flag the position is ignored", t, func() {
+		mt := NewMatcher("aab")
+		mt.SetPos(2)
+		So(mt.M(`m/a/`), ShouldBeTrue)
+		So(mt.Last.Offsets, ShouldResemble, []int{0, 1})
+		So(mt.Pos(), ShouldEqual, 2)
+	})
+	Convey("SetPos and Reset", t, func() {
+		mt := NewMatcher("kalle ankka")
+		mt.SetPos(6)
+		So(mt.M(`m/(\w+)/g`), ShouldBeTrue)
+		So(mt.Last.S[1], ShouldEqual, "ankka")
+		mt.SetPos(100)
+		So(mt.Pos(), ShouldEqual, 11)
+		mt.SetPos(-1)
+		So(mt.Pos(), ShouldEqual, 0)
+		So(mt.Next(), ShouldBeTrue)
+		So(mt.Last.S[1], ShouldEqual, "kalle")
+		mt.Reset()
+		So(mt.Pos(), ShouldEqual, 0)
+		So(NewMatcher("x").Next(), ShouldBeFalse)
+	})
+	Convey("empty matches advance like m//g", t, func() {
+		mt := NewMatcher("ab")
+		var offsets []int
+		for mt.M(`m/x*/g`) {
+			offsets = append(offsets, mt.Pos())
+		}
+		So(offsets, ShouldResemble, []int{0, 1, 2})
+		mt.SetPos(1)
+		So(mt.Next(), ShouldBeTrue)
+		So(mt.Pos(), ShouldEqual, 1)
+	})
+	Convey("multibyte text", t, func() {
+		mt := NewMatcher("ab bb cc")
+		So(mt.M(`m/([bc])[bc]/g`), ShouldBeTrue)
+		So(mt.Last.S[1], ShouldEqual, "b")
+		So(mt.Next(), ShouldBeTrue)
+		So(mt.Last.S[1], ShouldEqual, "c")
+		So(mt.Next(), ShouldBeFalse)
+
+		mt = NewMatcher("äö")
+		var offsets []int
+		for mt.M(`m/x*/g`) {
+			offsets = append(offsets, mt.Pos())
+		}
+		So(offsets, ShouldResemble, []int{0, 2, 4})
+	})
+	Convey("overlapping matches with the w flag", t, func() {
+		for _, c := range []struct{ haystack, needle string }{
+			{"aaa", `m/aa/gw`}, {"aab", `m/a*/gw`}, {"äöü", `m/../gw`}, {"abcd", `m/(\w)(\w)/gw`},
+		} {
+			var spans [][2]int
+			mt := NewMatcher(c.haystack)
+			for mt.M(c.needle) {
+				spans = append(spans, [2]int{mt.Last.Start(0), mt.Last.End(0)})
+			}
+			var want [][2]int
+			for _, m := range Mr(c.haystack, c.needle).All() {
+				want = append(want, m.Span)
+			}
+			So(spans, ShouldResemble, want)
+		}
+		mt := NewMatcher("aaa")
+		So(mt.M(`m/aa/gw`), ShouldBeTrue)
+		So(mt.Pos(), ShouldEqual, 1)
+	})
+	Convey("the latest match is the last result of the session", t, func() {
+		sess := NewSession()
+		mt := sess.NewMatcher("abc")
+		mt.M(`m/b/g`)
+		So(sess.Last, ShouldEqual, mt.Last)
+	})
+	Convey("invalid needles", t, func() {
+		mt := NewMatcher("abc")
+		_, err := mt.ME(`s/a/b/`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		_, err = mt.ME(`m/(/`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		So(func() { mt.M(`tr/a/b/`) }, ShouldPanic)
+		runErrorTest(`s/a/b/c`, ErrSyntax)
+		runErrorTest(`tr/a/b/gc`, ErrSyntax)
+	})
+}
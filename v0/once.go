@@ -0,0 +1,88 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+/*
+match runs the m// needle, honouring the match-once rule of m?...?: after it has matched, the needle fails without searching until Reset.
+Only one of the goroutines sharing the Session gets the match, like with Pattern.match.
+*/
+func (self *Session) match(haystack *string, r *RE, needle string) *RE {
+	if !r.once {
+		return m(haystack, r)
+	}
+	self.mu.Lock()
+	matched := self.matched[needle]
+	self.mu.Unlock()
+	if matched {
+		return r
+	}
+	unmatched := *r
+	m(haystack, r)
+	if r.Matches == 0 {
+		return r
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.matched[needle] {
+		return &unmatched // another goroutine matched first
+	}
+	if self.matched == nil {
+		self.matched = make(map[string]bool)
+	}
+	self.matched[needle] = true
+	return r
+}
+
+/*
+Reset lets every m?...? needle of the Session match once again, like Perl's reset.
+
+	for _, file := range files {
+		Default().Reset()
+		for _, line := range lines(file) {
+			if M(line, `m?^Subject: (.*)?`) {
+				subjects[file] = R0.S[1] // only the first Subject of each file
+			}
+		}
+	}
+
+The rule applies to M, Mr, ME and R, and to Pattern.Match and Pattern.MatchAll. Matcher and the iterators like All search every time.
+*/
+func (self *Session) Reset() {
+	self.mu.Lock()
+	self.matched = nil
+	self.mu.Unlock()
+}
+
+/*
+Reset lets the Pattern of a m?...? needle match once again, see Session.Reset.
+*/
+func (p *Pattern) Reset() {
+	if p.matched != nil {
+		p.matched.Store(false)
+	}
+}
+
+/*
+match runs the Pattern, honouring the match-once rule of m?...? across the goroutines using it
+*/
+func (p *Pattern) match(haystack string, r *RE) *RE {
+	if p.matched == nil {
+		return m(&haystack, r)
+	}
+	if p.matched.Load() {
+		return r
+	}
+	unmatched := *r
+	m(&haystack, r)
+	if r.Matches > 0 && !p.matched.CompareAndSwap(false, true) {
+		return &unmatched // another goroutine matched first
+	}
+	return r
+}
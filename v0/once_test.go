@@ -0,0 +1,143 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleSession_Reset() {
+	files := map[string][]string{
+		"a.eml": {"From: kalle", "Subject: hello", "Subject: again"},
+		"b.eml": {"Subject: hi", "From: ankka"},
+	}
+	sess := NewSession()
+	for _, name := range []string{"a.eml", "b.eml"} {
+		sess.Reset()
+		for _, line := range files[name] {
+			if sess.M(line, `m?^Subject: (.*)?`) {
+				fmt.Println(name, sess.Last.S[1])
+			}
+		}
+	}
+	// Output:
+	// a.eml hello
+	// b.eml hi
+}
+
+func TestMatchOnce(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("m?...? matches only once until Reset", t, func() {
+		sess := NewSession()
+		So(sess.M("pesus", `m?a?`), ShouldBeFalse)
+		So(sess.M("ankka", `m?a?`), ShouldBeTrue)
+		So(sess.M("ankka", `m?a?`), ShouldBeFalse)
+		So(sess.Last.Matches, ShouldEqual, 0)
+		So(sess.M("ankka", `m/a/`), ShouldBeTrue)
+		So(sess.M("ankka", `m/a/`), ShouldBeTrue)
+		So(sess.M("ankka", `m?k?`), ShouldBeTrue)
+
+		sess.Reset()
+		r, err := sess.ME("ankka", `m?a?`)
+		So(err, ShouldBeNil)
+		So(r.Matches, ShouldEqual, 1)
+		str := "ankka"
+		So(sess.R(&str, `m?a?`), ShouldBeFalse)
+		So(sess.R(&str, `s?a?b?`), ShouldBeTrue)
+		So(sess.R(&str, `s?b?c?`), ShouldBeTrue)
+		So(str, ShouldEqual, "cnkka")
+	})
+	Convey("flags and escapes", t, func() {
+		sess := NewSession()
+		r := sess.Mr("Kalle? Ankka", `m?(\w+)\? (\w+)?gi`)
+		So(r.S, ShouldResemble, []string{"", "Kalle", "Ankka"})
+		So(sess.M("Kalle? Ankka", `m?(\w+)\? (\w+)?gi`), ShouldBeFalse)
+	})
+	Convey("sessions do not share the state", t, func() {
+		a, b := NewSession(), NewSessionSharingCache(Default())
+		So(a.M("a", `m?a?`), ShouldBeTrue)
+		So(b.M("a", `m?a?`), ShouldBeTrue)
+		So(a.M("a", `m?a?`), ShouldBeFalse)
+	})
+	Convey("a Pattern matches once until its Reset", t, func() {
+		p := MustQr(`m?(\d+)?`)
+		So(p.Match("x").Matches, ShouldEqual, 0)
+		So(p.MatchAll("1 2").S, ShouldResemble, []string{"", "1", "2"})
+		So(p.Match("3").Matches, ShouldEqual, 0)
+		p.Reset()
+		So(p.Match("3").S, ShouldResemble, []string{"", "3"})
+
+		q := MustQr(`m/(\d+)/`)
+		q.Reset()
+		So(q.Match("1").Matches, ShouldEqual, 1)
+		So(q.Match("1").Matches, ShouldEqual, 1)
+	})
+	Convey("only one goroutine matches", t, func() {
+		p := MustQr(`m?a?`)
+		var wg sync.WaitGroup
+		var matched atomic.Int32
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if p.Match("a").Matches > 0 {
+					matched.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		So(matched.Load(), ShouldEqual, 1)
+
+		sess := NewSession()
+		matched.Store(0)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				haystack, needle := "a", `m?a?`
+				if sess.match(&haystack, sess.regexParser(&needle), needle).Matches > 0 {
+					matched.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		So(matched.Load(), ShouldEqual, 1)
+	})
+	Convey("Reset does not race with matching", t, func() {
+		sess := NewSession()
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				haystack, needle := "a", fmt.Sprintf(`m?a%d?`, i%2)
+				sess.match(&haystack, sess.regexParser(&needle), needle)
+				sess.Reset()
+			}()
+		}
+		wg.Wait()
+		So(sess.matched, ShouldBeNil)
+	})
+	Convey("Matcher and the iterators search every time", t, func() {
+		mt := NewMatcher("aa")
+		So(mt.M(`m?a?g`), ShouldBeTrue)
+		So(mt.M(`m?a?g`), ShouldBeTrue)
+		count := 0
+		for range All("aa", `m?a?`) {
+			count++
+		}
+		So(count, ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,109 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+/*
+SubstOptions choose which of the matches a substitution replaces, see SrWith.
+Nth and Limit choose from all the matches of the haystack, with or without the g flag. Otherwise the substitution goes past the first match only with the g flag,
+so the zero value replaces what the needle would without options. The options apply in the order From, Nth, Limit.
+
+	SrWith(&str, `s/a/b/`, SubstOptions{Nth: 3})            // the third match
+	SrWith(&str, `s/a/b/`, SubstOptions{Nth: -1})           // the last match
+	SrWith(&str, `s/a/b/`, SubstOptions{From: 10, Limit: 5}) // at most 5 matches starting at or after byte offset 10
+*/
+type SubstOptions struct {
+	From  int // the search starts at this byte offset of the haystack, where \G matches, the text before it is still seen by \b and the like
+	Nth   int // only the Nth of those matches counting from 1, or from the last one if negative, 0 for the first one, or all of them with the g flag
+	Limit int // at most this many of those matches, 0 for no limit
+}
+
+/*
+SrWith is Sr replacing only the matches chosen by the options. Matches is the number of substitutions made, and S and Z hold the captures of the replaced matches only.
+*/
+func SrWith(haystack *string, needle string, opts SubstOptions) *RE {
+	return defaultSession.SrWith(haystack, needle, opts)
+}
+
+/*
+SEWith is SrWith, but returns an error instead of panicking on an invalid needle.
+*/
+func SEWith(haystack *string, needle string, opts SubstOptions) (*RE, error) {
+	return defaultSession.SEWith(haystack, needle, opts)
+}
+
+func (self *Session) SrWith(haystack *string, needle string, opts SubstOptions) *RE {
+	r := self.regexParser(&needle)
+	r.opts = &opts
+	self.setLast(r)
+	return s(haystack, r)
+}
+
+func (self *Session) SEWith(haystack *string, needle string, opts SubstOptions) (*RE, error) {
+	r, err := self.regexParserE(&needle)
+	if err != nil {
+		return nil, err
+	}
+	r.opts = &opts
+	self.setLast(r)
+	return se(haystack, r, nil)
+}
+
+/*
+SubstWith returns the haystack with the matches chosen by the options substituted, just like SrWith.
+*/
+func (p *Pattern) SubstWith(haystack string, opts SubstOptions) (string, *RE) {
+	r := *p.re
+	r.opts = &opts
+	s(&haystack, &r)
+	return haystack, &r
+}
+
+/*
+limit is how many matches to look for to choose from, past the first one only with the g flag or when Nth or Limit asks for more
+*/
+func (o *SubstOptions) limit(g bool) int {
+	switch {
+	case o.Nth < 0:
+		return -1
+	case o.Nth > 0:
+		return o.Nth
+	case o.Limit > 0:
+		return o.Limit
+	case g:
+		return -1
+	}
+	return 1
+}
+
+/*
+choose picks the matches to substitute from those found searching from From, nil if none
+*/
+func (o *SubstOptions) choose(matches [][]int, g bool) [][]int {
+	if o.Nth != 0 {
+		n := o.Nth - 1
+		if o.Nth < 0 {
+			n = len(matches) + o.Nth
+		}
+		if n < 0 || n >= len(matches) {
+			return nil
+		}
+		matches = matches[n : n+1]
+	}
+	if o.Limit > 0 && o.Limit < len(matches) {
+		matches = matches[:o.Limit]
+	}
+	if o.Nth == 0 && o.Limit == 0 && !g && len(matches) > 1 {
+		matches = matches[:1]
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches
+}
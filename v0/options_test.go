@@ -0,0 +1,102 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleSrWith() {
+	str := "a a a a a"
+	SrWith(&str, `s/a/b/`, SubstOptions{Nth: 3})
+	fmt.Println(str)
+	SrWith(&str, `s/a/c/`, SubstOptions{Nth: -1})
+	fmt.Println(str)
+	// Output: a a b a a
+	// a a b a c
+}
+
+func TestSubstOptions(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("choosing the matches", t, func() {
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{}, "<1> 2 3 4 5", 1, []string{"", "1"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{Nth: 3}, "1 2 <3> 4 5", 1, []string{"", "3"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{Nth: -1}, "1 2 3 4 <5>", 1, []string{"", "5"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{Nth: -5}, "<1> 2 3 4 5", 1, []string{"", "1"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{Limit: 2}, "<1> <2> 3 4 5", 2, []string{"", "1", "2"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{From: 2}, "1 <2> 3 4 5", 1, []string{"", "2"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{From: 2, Limit: 2}, "1 <2> <3> 4 5", 2, []string{"", "2", "3"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{From: 2, Nth: 2}, "1 2 <3> 4 5", 1, []string{"", "3"})
+		runSubstOptionsTest("1 2 3 4 5", `s/(\d)/<$1>/`, SubstOptions{From: 2, Nth: -2}, "1 2 3 <4> 5", 1, []string{"", "4"})
+	})
+	Convey("the g flag makes no difference to Nth and Limit", t, func() {
+		runSubstOptionsTest("1 2 3", `s/\d/x/g`, SubstOptions{Nth: 2}, "1 x 3", 1, nil)
+		runSubstOptionsTest("1 2 3", `s/\d/x/g`, SubstOptions{Limit: 1}, "x 2 3", 1, nil)
+	})
+	Convey("without Nth and Limit only the g flag goes past the first match", t, func() {
+		runSubstOptionsTest("1 2 3", `s/\d/x/g`, SubstOptions{}, "x x x", 3, nil)
+		runSubstOptionsTest("1 2 3", `s/\d/x/g`, SubstOptions{From: 2}, "1 x x", 2, nil)
+		runSubstOptionsTest("1 2 3", `s/\d/x/`, SubstOptions{From: 1}, "1 x 3", 1, nil)
+	})
+	Convey("nothing to substitute", t, func() {
+		runSubstOptionsTest("1 2 3", `s/(\d)/x/`, SubstOptions{Nth: 4}, "1 2 3", 0, nil)
+		runSubstOptionsTest("1 2 3", `s/(\d)/x/`, SubstOptions{Nth: -4}, "1 2 3", 0, nil)
+		runSubstOptionsTest("1 2 3", `s/(\d)/x/`, SubstOptions{From: 6}, "1 2 3", 0, nil)
+		runSubstOptionsTest("1 2 3", `s/(\d)/x/`, SubstOptions{From: 100}, "1 2 3", 0, nil)
+	})
+	Convey("the search starts at From", t, func() {
+		runSubstOptionsTest("aaaa", `s/aa/x/`, SubstOptions{From: 1}, "axa", 1, nil)
+		runSubstOptionsTest("aaaaa", `s/aa/x/g`, SubstOptions{From: 1}, "axx", 2, nil)
+		runSubstOptionsTest("abab", `s/\Gab/x/g`, SubstOptions{From: 2}, "abx", 1, nil)
+		runSubstOptionsTest("1 2 3", `s/\d/x/g`, SubstOptions{From: -1}, "x x x", 3, nil)
+		runSubstOptionsTest("ab ab", `s/\bab/x/`, SubstOptions{From: 1}, "ab x", 1, nil)
+		runSubstOptionsTest("abab", `s/(?<x>ab)(?:ab)?/x/`, SubstOptions{Nth: 1}, "x", 1, []string{"", "ab"})
+	})
+	Convey("named captures, expressions, the k and r flags", t, func() {
+		runSubstOptionsTest("a1 b2 c3", `s/(?<l>\w)(\d)/$2 * 10/e`, SubstOptions{Nth: 2}, "a1 20 c3", 1, []string{"", "b", "2"})
+		str := "a1 b2 c3"
+		So(SrWith(&str, `s/(?<l>\w)(\d)/x/`, SubstOptions{Nth: -1}).Z, ShouldResemble, map[string]string{"l": "c"})
+		runSubstOptionsTest("Colour colour", `s/colour/color/ik`, SubstOptions{Nth: -1}, "Colour color", 1, nil)
+		str = "1 2"
+		r := SrWith(&str, `s/\d/x/r`, SubstOptions{Nth: 2})
+		So(str, ShouldEqual, "1 2")
+		So(r.Result, ShouldEqual, "1 x")
+	})
+	Convey("Sessions and Patterns", t, func() {
+		str := "1 2 3"
+		_, err := SEWith(&str, `s/(\d/x/`, SubstOptions{Nth: 2})
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		So(func() { SrWith(&str, `s/(\d/x/`, SubstOptions{}) }, ShouldPanic)
+		r, err := SEWith(&str, `s/\d/x/`, SubstOptions{Nth: 2})
+		So(err, ShouldBeNil)
+		So(str, ShouldEqual, "1 x 3")
+		So(R0, ShouldEqual, r)
+
+		p := MustQr(`s/\d/x/`)
+		str, r = p.SubstWith("1 2 3", SubstOptions{Limit: 2})
+		So(str, ShouldEqual, "x x 3")
+		So(r.Matches, ShouldEqual, 2)
+		str, _ = p.Subst("1 2 3")
+		So(str, ShouldEqual, "x 2 3")
+	})
+}
+
+func runSubstOptionsTest(haystack string, needle string, opts SubstOptions, expected string, matches int, captures []string) {
+	Convey(fmt.Sprintf(`"%s" with %+v substitutes "%s" to "%s"`, needle, opts, haystack, expected), func() {
+		r := SrWith(&haystack, needle, opts)
+		So(haystack, ShouldEqual, expected)
+		So(r.Matches, ShouldEqual, matches)
+		So(r.S, ShouldResemble, captures)
+	})
+}
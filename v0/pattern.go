@@ -0,0 +1,256 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+Pattern is a needle parsed once, like Perl's qr//
+
+	date := MustQr(`m/(?P<year>\d{4})-(?P<month>\d\d)-(?P<day>\d\d)/`)
+	for _, line := range lines {
+		if r := date.Match(line); r.Matches > 0 {
+			year := r.Z["year"]
+		}
+	}
+
+Operations through a Pattern skip the needle parser and the regexp cache, and do not touch R0.
+A Pattern is immutable and safe for concurrent use, every operation returns a fresh *RE. The only state is whether a m?...? Pattern has matched, see Reset.
+*/
+type Pattern struct {
+	re      *RE          // the parsed needle, copied for every operation
+	all     *RE          // the parsed needle with the g flag forced on
+	matched *atomic.Bool // a m?...? needle has matched since Reset, nil for other needles
+}
+
+/*
+Qr parses the needle into a reusable Pattern.
+*/
+func Qr(needle string) (*Pattern, error) {
+	return defaultSession.Qr(needle)
+}
+
+/*
+MustQr is the "must" flavour of Qr, it panics with a *ParseError if the needle is invalid.
+*/
+func MustQr(needle string) *Pattern {
+	p, err := Qr(needle)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+/*
+FromRegexp wraps an already compiled Go regexp into a match Pattern without flags. See WithReplacement to substitute with it.
+*/
+func FromRegexp(regex *regexp.Regexp) *Pattern {
+	n, s, f := regex.String(), "", ""
+	r := &RE{
+		_orig:     n,
+		n:         &n,
+		s:         &s,
+		f:         &f,
+		regex:     regex,
+		from:      &searchFrom{},
+		mode:      'm',
+		separator: '/',
+	}
+	captureAnalyser(r) // cannot fail, the regexp is already compiled
+	return newPattern(r)
+}
+
+func newPattern(r *RE) *Pattern {
+	all := *r
+	if !strings.Contains(*r.f, "g") {
+		f := *r.f + "g"
+		all.f = &f
+	}
+	p := &Pattern{re: r, all: &all}
+	if r.once && r.mode == 'm' {
+		p.matched = &atomic.Bool{}
+	}
+	return p
+}
+
+/*
+WithReplacement is the "must" flavour of WithReplacementE, it panics with a *ParseError if the replacement is invalid.
+*/
+func (p *Pattern) WithReplacement(replacement string) *Pattern {
+	q, err := p.WithReplacementE(replacement)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+/*
+WithReplacementE returns a substitution Pattern using the same matching pattern and flags, but the given replacement string.
+The replacement is parsed like the replacement half of s///, and the *ParseError of an invalid one shows it in the s/// needle it makes with the matching pattern.
+Only a m// Pattern can be given a replacement, s/// and tr/// Patterns are an ErrSyntax.
+*/
+func (p *Pattern) WithReplacementE(replacement string) (*Pattern, error) {
+	if p.re.mode != 'm' {
+		return nil, newParseError(p.re, ErrSyntax, ComponentMode, 0, "only a m// Pattern can be given a replacement")
+	}
+	needle, sOff := p.substNeedle(replacement)
+	r := *p.re
+	r._orig = needle
+	r.mode = 's'
+	r.s = &replacement
+	r.sPos = make([]int, len(replacement))
+	for i := range r.sPos {
+		r.sPos[i] = sOff + i
+	}
+	repl, err := replacementTemplate(&r, replacement, r.sPos, false)
+	if err != nil {
+		return nil, err
+	}
+	r.repl = repl
+	r.evals, r.expr = 0, nil
+	return newPattern(&r), nil
+}
+
+/*
+substNeedle spells the s/// needle of the matching pattern with the replacement, and the offset of the replacement in it.
+The matching pattern keeps its offsets, except that of a FromRegexp Pattern, which is written between slashes.
+*/
+func (p *Pattern) substNeedle(replacement string) (string, int) {
+	r := p.re
+	if r.nEnd == 0 {
+		return "s/" + r._orig + "/" + replacement + "/", len(r._orig) + 3 // FromRegexp
+	}
+	head := "s" + strings.TrimPrefix(r._orig[:r.nEnd+1], "m")
+	if closingDelimiter(r.separator) != r.separator {
+		head += string(r.separator)
+	}
+	return head + replacement + string(closingDelimiter(r.separator)) + r._orig[r.fOff:], len(head)
+}
+
+/*
+Match runs the matching pattern against the haystack, honouring the flags of the needle, just like Mr.
+*/
+func (p *Pattern) Match(haystack string) *RE {
+	r := *p.re
+	return p.match(haystack, &r)
+}
+
+/*
+MatchAll is Match with the g flag.
+*/
+func (p *Pattern) MatchAll(haystack string) *RE {
+	r := *p.all
+	return p.match(haystack, &r)
+}
+
+/*
+Subst returns the substituted haystack, honouring the flags of the needle, just like Sr.
+Like Sr it panics with a *ParseError if evaluating the replacement expression of s///e fails.
+*/
+func (p *Pattern) Subst(haystack string) (string, *RE) {
+	r := *p.re
+	s(&haystack, &r)
+	return haystack, &r
+}
+
+/*
+SubstAll is Subst with the g flag.
+*/
+func (p *Pattern) SubstAll(haystack string) (string, *RE) {
+	r := *p.all
+	s(&haystack, &r)
+	return haystack, &r
+}
+
+/*
+Split slices the haystack into substrings separated by the matching pattern, see regexp.Regexp.Split for the meaning of n.
+*/
+func (p *Pattern) Split(haystack string, n int) []string {
+	if p.re.regex == nil {
+		return []string{haystack} // tr/// has nothing to split with
+	}
+	if n == 0 {
+		return nil
+	}
+	if len(p.re.body()) > 0 && len(haystack) == 0 {
+		return []string{""}
+	}
+
+	matches := p.re.findAll(haystack, 0, n)
+	parts := make([]string, 0, len(matches))
+	beg, end := 0, 0
+	for _, match := range matches {
+		if n > 0 && len(parts) == n-1 {
+			break
+		}
+		end = match[0]
+		if match[1] != 0 {
+			parts = append(parts, haystack[beg:end])
+		}
+		beg = match[1]
+	}
+	if end != len(haystack) {
+		parts = append(parts, haystack[beg:])
+	}
+	return parts
+}
+
+/*
+Regexp returns the underlying compiled Go regexp, nil for tr///.
+*/
+func (p *Pattern) Regexp() *regexp.Regexp {
+	return p.re.regex
+}
+
+/*
+Needle returns the needle the Pattern was parsed from.
+*/
+func (p *Pattern) Needle() string {
+	return p.re._orig
+}
+
+/*
+Mode returns the operation of the needle, "m", "s" or "t" for tr///.
+*/
+func (p *Pattern) Mode() string {
+	return string(p.re.mode)
+}
+
+/*
+Flags returns the flags of the needle.
+*/
+func (p *Pattern) Flags() string {
+	return *p.re.f
+}
+
+/*
+Replacement returns the replacement string of a substitution needle.
+*/
+func (p *Pattern) Replacement() string {
+	return *p.re.s
+}
+
+/*
+NumCaptures returns the number of capture groups in the matching pattern.
+*/
+func (p *Pattern) NumCaptures() int {
+	return p.re.NumCaptures()
+}
+
+/*
+CaptureNames returns the names of the capture groups, indexed like RE.S.
+*/
+func (p *Pattern) CaptureNames() []string {
+	return p.re.CaptureNames()
+}
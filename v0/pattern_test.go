@@ -0,0 +1,87 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleQr() {
+	date := MustQr(`m/(?P<year>\d{4})-(?P<month>\d\d)-(?P<day>\d\d)/`)
+	for _, line := range []string{"released 2021-12-31", "no date here", "patched 2022-01-02"} {
+		if r := date.Match(line); r.Matches > 0 {
+			fmt.Printf("%s/%s\n", r.Z["month"], r.Z["year"])
+		}
+	}
+	// Output: 12/2021
+	// 01/2022
+}
+
+func TestPattern(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("Qr", t, func() {
+		_, err := Qr(`m/(a./`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		So(func() { MustQr(`m/(a./`) }, ShouldPanic)
+
+		p, err := Qr(`s/(a.)/--/i`)
+		So(err, ShouldBeNil)
+		So(p.Needle(), ShouldEqual, `s/(a.)/--/i`)
+		So(p.Mode(), ShouldEqual, "s")
+		So(p.Flags(), ShouldEqual, "i")
+		So(p.Replacement(), ShouldEqual, "--")
+		So(p.Regexp().String(), ShouldEqual, `(?i)(a.)`)
+	})
+	Convey("matching", t, func() {
+		p := MustQr(`m/(a.)/`)
+		r := p.Match("kalle ankka")
+		So(r.Matches, ShouldEqual, 1)
+		So(r.S, ShouldResemble, []string{"", "al"})
+		r = p.MatchAll("kalle ankka")
+		So(r.Matches, ShouldEqual, 2)
+		So(r.S, ShouldResemble, []string{"", "al", "an"})
+		r = p.Match("bubbelbubbe")
+		So(r.Matches, ShouldEqual, 0)
+		So(r.S, ShouldBeNil)
+		So(p.Match("kalle ankka").S, ShouldResemble, []string{"", "al"})
+	})
+	Convey("matching does not touch R0", t, func() {
+		M("kalle", `m/kalle/`)
+		r0 := R0
+		MustQr(`m/ankka/`).Match("kalle ankka")
+		So(R0, ShouldEqual, r0)
+	})
+	Convey("substitution", t, func() {
+		p := MustQr(`s/(a.)/--/`)
+		str, r := p.Subst("kalle ankka")
+		So(str, ShouldEqual, "k--le ankka")
+		So(r.Matches, ShouldEqual, 1)
+		str, r = p.SubstAll("kalle ankka")
+		So(str, ShouldEqual, "k--le --kka")
+		So(r.Matches, ShouldEqual, 2)
+		So(r.S, ShouldResemble, []string{"", "al", "an"})
+	})
+	Convey("splitting", t, func() {
+		So(MustQr(`m/\s*,\s*/`).Split("kalle , ankka,minni", -1), ShouldResemble, []string{"kalle", "ankka", "minni"})
+	})
+	Convey("FromRegexp", t, func() {
+		p := FromRegexp(regexp.MustCompile(`(?P<first>a.)`))
+		So(p.Mode(), ShouldEqual, "m")
+		So(p.Match("kalle ankka").Z, ShouldResemble, map[string]string{"first": "al"})
+		str, r := p.WithReplacement("${first}!").SubstAll("kalle ankka")
+		So(str, ShouldEqual, "kal!le an!kka")
+		So(r.Matches, ShouldEqual, 2)
+	})
+}
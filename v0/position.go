@@ -0,0 +1,100 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Position is an offset in the haystack as a 1-based line and column, see RE.Locate.
+*/
+type Position struct {
+	Offset     int // byte offset in the haystack
+	Line       int // 1-based line number
+	Column     int // 1-based column in runes, like ParseError.Position
+	ByteColumn int // 1-based column in bytes, like the go tools
+}
+
+/*
+String formats the position as line:col
+*/
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+/*
+Where formats the position in the file:line:col convention of the Go tools, with the byte column like gofmt and go vet report.
+*/
+func (p Position) Where(file string) string {
+	return fmt.Sprintf("%s:%d:%d", file, p.Line, p.ByteColumn)
+}
+
+/*
+Locate fills the Positions of each Match in All. The haystack is scanned once for all the matches, however many there are.
+
+	for _, m := range Mr(src, `m/\bTODO\b/g`).Locate().All() {
+		fmt.Println(m.Positions[0].Where("main.go"), "TODO found")
+	}
+*/
+func (r *RE) Locate() *RE {
+	all := r.All()
+	if len(all) == 0 {
+		return r
+	}
+	var offsets []int
+	for _, m := range all {
+		for _, offset := range m.Offsets {
+			if offset >= 0 {
+				offsets = append(offsets, offset)
+			}
+		}
+	}
+	positions := locate(r.haystack, offsets)
+	for i := range all {
+		m := &all[i]
+		m.Positions = make([]Position, len(m.Offsets))
+		for j, offset := range m.Offsets {
+			if offset >= 0 {
+				m.Positions[j] = positions[offset]
+			}
+		}
+	}
+	return r
+}
+
+/*
+locate finds the positions of the offsets, counting the newlines and runes only once from the previous offset to the next.
+*/
+func locate(haystack string, offsets []int) map[int]Position {
+	sort.Ints(offsets)
+	positions := make(map[int]Position, len(offsets))
+	p := Position{Line: 1, Column: 1, ByteColumn: 1}
+	lineStart := 0
+	for _, offset := range offsets {
+		if offset == p.Offset && len(positions) > 0 {
+			continue
+		}
+		skipped := haystack[p.Offset:offset]
+		if newlines := strings.Count(skipped, "\n"); newlines > 0 {
+			p.Line += newlines
+			lineStart = p.Offset + strings.LastIndexByte(skipped, '\n') + 1
+			p.Column = utf8.RuneCountInString(haystack[lineStart:offset]) + 1
+		} else {
+			p.Column += utf8.RuneCountInString(skipped)
+		}
+		p.Offset, p.ByteColumn = offset, offset-lineStart+1
+		positions[offset] = p
+	}
+	return positions
+}
@@ -0,0 +1,62 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleRE_Locate() {
+	src := "package main\n\n// TODO: tests\nfunc main() {} // TODO: docs\n"
+	for _, m := range Mr(src, `m/TODO: (\w+)/g`).Locate().All() {
+		fmt.Println(m.Positions[0].Where("main.go"), m.Groups[1])
+	}
+	// Output: main.go:3:4 tests
+	// main.go:4:19 docs
+}
+
+func TestLocate(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("lines and columns", t, func() {
+		r := Mr("kalle\nankka\n\nääkkönen ja (hiiri)", `m/(a)n|(k)ö|\((\w+)\)/g`).Locate()
+		So(len(r.All()), ShouldEqual, 3)
+		So(r.All()[0].Positions[0], ShouldResemble, Position{Offset: 6, Line: 2, Column: 1, ByteColumn: 1})
+		So(r.All()[0].Positions[1], ShouldResemble, Position{Offset: 8, Line: 2, Column: 3, ByteColumn: 3})
+		So(r.All()[0].Positions[4], ShouldResemble, Position{})
+		So(r.All()[1].Positions[0], ShouldResemble, Position{Offset: 18, Line: 4, Column: 4, ByteColumn: 6})
+		So(r.All()[2].Positions[0].String(), ShouldEqual, "4:13")
+		So(r.All()[2].Positions[6], ShouldResemble, Position{Offset: 29, Line: 4, Column: 14, ByteColumn: 17})
+		So(r.All()[2].Positions[7].Where("x.txt"), ShouldEqual, "x.txt:4:22")
+	})
+	Convey("the first line and the end of the haystack", t, func() {
+		r := Mr("kalle\n", `m/^|$/gm`).Locate()
+		So(len(r.All()), ShouldEqual, 3)
+		So(r.All()[0].Positions[0].String(), ShouldEqual, "1:1")
+		So(r.All()[1].Positions[0].String(), ShouldEqual, "1:6")
+		So(r.All()[2].Positions[0].String(), ShouldEqual, "2:1")
+	})
+	Convey("substitutions and matches without positions", t, func() {
+		str := "a\nb"
+		r := Sr(&str, `s/b/xx/`).Locate()
+		So(r.All()[0].Positions[0].String(), ShouldEqual, "2:1")
+		So(Mr("kalle", `m/x/`).Locate().All(), ShouldBeNil)
+		So(Mr("kalle", `m/a/`).All()[0].Positions, ShouldBeNil)
+	})
+	Convey("large inputs", t, func() {
+		line := "kalle ankka TODO\n"
+		r := Mr(strings.Repeat(line, 10000), `m/TODO/g`).Locate()
+		So(len(r.All()), ShouldEqual, 10000)
+		So(r.All()[9999].Positions[0], ShouldResemble, Position{Offset: 9999*len(line) + 12, Line: 10000, Column: 13, ByteColumn: 13})
+	})
+}
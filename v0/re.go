@@ -28,44 +28,129 @@ Flags supported:
  - m
  - s
  - i
+ - e, the replacement is an expression, ee evaluates its result again, see Se for a Go callback instead
+ - r, s/// and tr/// leave the haystack untouched and return the new string in Result, see Chain for a sequence of substitutions
+ - c, a failing m//g keeps the position of a Matcher
+ - w, not in Perl, m//g finds overlapping matches, restarting one character after the start of each match, eg. m/\w\w/gw finds the bigrams
+ - k, not in Perl, s/// keeps the case of the matched text, s/colour/color/gik turns "Colour" into "Color" and "COLOUR" into "COLOR"
+
+Delimiters:
+
+ Any character can separate the components of the needle, eg. m/.../ or m!...!. The bracketing delimiters (), [], {} and <> nest,
+ and give the replacement its own delimiters: s{...}{...}, s<...>/.../. Whitespace, and with the x flag comments, may separate the halves.
+ m?...? matches only once, until Session.Reset or Pattern.Reset is called.
+
+Transliteration:
+
+ tr/SEARCH/REPLACE/ and its alias y/SEARCH/REPLACE/ support the flags c, d, s and r. Matches is the number of characters found in SEARCH.
+
+Replacements:
+
+ The replacement of s/// is a Perl double-quoted string: $1, ${1}, \1, $&, $`, $', $+, $+{name}, ${^MATCH}, ${^PREMATCH}, ${^POSTMATCH},
+ and the escapes \n, \t, \x{263A}, \N{U+263A}, ... work. $name and ${name} are named groups too, $0 is the whole match and $$ is a dollar sign.
+ \u, \l, \U, \L, \F, \Q and \E modify the case like in Perl, eg. s/(\w+)/\u\L$1/g titlecases words.
+ The x flag only applies to the matching pattern. With ' as the delimiter, s'...'...', the replacement is not interpolated.
+
+Replacement expressions:
+
+ With the e flag the replacement is an expression in a small sandboxed language, eg. s/(\d+)/$1 * 2/eg, with numbers, '...' and "..." strings,
+ the variables of replacements, the operators + - * / % ** . x ! == != < > <= >= <=> eq ne lt gt le ge cmp && || ?: and the functions
+ lc, uc, lcfirst, ucfirst, length, substr, sprintf, int and abs. Functions need parentheses. Nothing can be read or written outside the match,
+ and evaluation is limited in steps and string length. Failures while substituting are *ParseErrors matching ErrEvaluation.
+
+Named groups:
+
+ Named groups can be written (?<name>...), (?'name'...) or (?P<name>...), and are all found in Z.
+ The Go engine has no backreferences nor lookbehinds, \1, \g1, \g{-1}, \k<name>, \k'name', \k{name}, \g{name}, (?P=name),
+ (?<=...) and (?<!...) fail with ErrUnsupportedFeature.
+
+Iterators:
+
+ All, AllNamed, Grep and Substitutions return range-over-func iterators over every match, the named groups of every match, the lines
+ matching the needle and the replacement of every match. The haystack is searched lazily, so breaking out of the loop stops the search.
+
+Anchors:
+
+ \G matches at the position where the previous match of m//g ended, or at the position of a Matcher, eg. m/\G\s*(\d+)/g only finds
+ adjacent numbers. It is only supported at the start of a top-level alternative.
+
+Errors:
+
+ M, Mr, S, Sr, Ss and R panic with a *ParseError if the needle is invalid.
+ ME, SE, SsE, RrE and Compile return the *ParseError instead, use them when the needle comes from configuration or user input.
+ The error can be tested with errors.Is against ErrSyntax, ErrMissingTerminator, ErrUnsupportedFeature and ErrTrailingAfterFlags,
+ or ErrEvaluation if the replacement expression of s///e fails for a match.
 
 Notes:
 
- - Named capture groups are overwritten with the last capture group when using global matching
+ - R0 and UseRECache belong to the default Session. Concurrent code should give each goroutine its own Session.
+ - Named capture groups are overwritten with the last capture group when using global matching, All() and ZAll have the captures of each match
  - Unicode might not work properly.
 
 */
 package re
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"regexp/syntax"
 	"strings"
 	"sync"
 )
 
 type RE struct {
-	_orig     string         // original regex string
-	f         *string        // altered flags string
-	n         *string        // altered regex string
-	s         *string        // substitution string in substitute-operation
-	regex     *regexp.Regexp // compiled regexp after preprocessing the needle parsed
-	mode      byte           // s or m or tr
-	separator byte           // separate the mode/matcher/substituter/flags components
-	captures  bool           // Enable capture group functionality
-	nCaptures bool           // Enable named capture groups functionality. The way Go regexp works with mixing named and non-named groups together makes it difficult to distinguish if named groups are actually used or not. This saves a lot of computation.
-
-	g bool // flag g used
-	x bool // flag x used
-
-	Matches int               // how many times the regex matched
-	S       []string          // $1, $2, ..., $n Captured subpatterns
-	Z       map[string]string // %+ Named capture buffers
+	_orig      string            // original regex string
+	f          *string           // altered flags string
+	n          *string           // altered regex string
+	s          *string           // substitution string in substitute-operation
+	regex      *regexp.Regexp    // compiled regexp after preprocessing the needle parsed
+	tr         *transliteration  // the search and replacement lists of tr///
+	mode       byte              // s or m or t(r)
+	separator  byte              // separate the mode/matcher/substituter/flags components, the opening one of bracketing delimiters
+	nPos       []int             // offset in _orig of each byte in n, for error reporting
+	sPos       []int             // offset in _orig of each byte in s, for error reporting
+	nEnd       int               // offset in _orig of the separator terminating the matching pattern
+	sEnd       int               // offset in _orig of the separator terminating the substitution string
+	fOff       int               // offset in _orig of the flags
+	midComment int               // offset in _orig of a comment between the bracketed halves of s{...} {...}, -1 if none
+	nPrefix    int               // length of the flags prefix flagHandlerGoNative prepends to n
+	warnings   []string          // non-fatal problems found while parsing
+	repl       []replacementPart // the parsed substitution string
+	groups     []CaptureGroup    // the capture groups of the parsed matching pattern
+	from       *searchFrom       // the regexp wrapped to search from an offset, see search
+	anchor     *anchor           // the emulated \G, nil if there is none
+	captures   bool              // Enable capture group functionality
+	nCaptures  bool              // Enable named capture groups functionality. The way Go regexp works with mixing named and non-named groups together makes it difficult to distinguish if named groups are actually used or not. This saves a lot of computation.
+
+	g     bool          // flag g used
+	x     bool          // flag x used
+	evals int           // how many times flag e is used, the replacement is an expression evaluated for each match, and the result evaluated again for each further e
+	expr  *exprNode     // the parsed replacement expression of s///e
+	r     bool          // flag r used with s///, the new string goes to Result and the haystack is left untouched
+	k     bool          // flag k used, the replacement keeps the case of the matched text, see preserveCase
+	opts  *SubstOptions // the matches to substitute, nil for the first one, or all of them with the g flag
+	c     bool          // flag c used, a failing match keeps the position of a Matcher
+	once  bool          // m?...? matches only once until Session.Reset or Pattern.Reset
+	w     bool          // flag w used, m//g finds overlapping matches, see each
+
+	Matches     int                 // how many times the regex matched, or how many characters tr/// transliterated
+	Index       int                 // the number of the match from 0, in the RE given to the callback of Se
+	Offsets     []int               // the start and end offsets in the haystack of $&, $1, ..., -1 for groups which did not match, in the RE given to the callback of Se
+	S           []string            // $1, $2, ..., $n Captured subpatterns
+	Z           map[string]string   // %+ Named capture buffers
+	ZAll        map[string][]string // %- every value of each named group, for each match and each group of the name, "" if it did not participate
+	ZAllMatched map[string][]bool   // for each value in ZAll, whether the group participated in the match, to tell an empty capture from no capture at all
+	Result      string              // the new string of a non-destructive operation using the r flag
+
+	haystack string     // the haystack of the matches, as it was before a substitution
+	indices  [][]int    // the start and end offsets of each match, see All
+	all      *matchList // All, built on first use
 }
 
-var R0 *RE = &RE{} // The result of the latest regexp operation. Not thread-safe! It could be if Go had thread-local variables or a way to identify the running thread.
+var R0 *RE = &RE{} // The result of the latest regexp operation of the default Session. Not thread-safe! Use a Session per goroutine instead.
 
-var UseRECache bool = true // Enable/Disable transparent RE caching. With caching enabled, the performance of repeated regex operations is increased ~600%
+var UseRECache bool = true // Enable/Disable transparent RE caching of the default Session. With caching enabled, the performance of repeated regex operations is increased ~600%
 
 /*
 Inspired by https://github.com/patrickmn/go-cache
@@ -106,7 +191,9 @@ func (self *reCache) Get(regex *string) *RE {
 	return &copy
 }
 func (self *reCache) Flush() {
+	self.mu.Lock()
 	self.cache = make(map[string]*RE)
+	self.mu.Unlock()
 }
 
 var regexpCache = newRECache()
@@ -115,146 +202,221 @@ var regexpCache = newRECache()
 R is useful if you don't know the type of the regex (match/substitute) beforehand and need to dynamically do things.
 */
 func R(haystack *string, needle string) bool {
-	r := regexParser(&needle)
-	if r.mode == 's' {
-		s(haystack, r)
-		if r.Matches > 0 {
-			return true
-		}
-	} else {
-		m(haystack, r)
-		if r.Matches > 0 {
-			return true
-		}
-		return false
-	}
+	return defaultSession.R(haystack, needle)
+}
+
+/*
+RrE is R, but returns the *RE and an error instead of panicking on an invalid needle.
+*/
+func RrE(haystack *string, needle string) (*RE, error) {
+	return defaultSession.RrE(haystack, needle)
+}
 
-	return false
+/*
+Compile parses and compiles the needle without running it against anything.
+Useful for validating needles coming from configuration or user input, the compiled needle is cached for the subsequent operations.
+*/
+func Compile(needle string) (*RE, error) {
+	return defaultSession.Compile(needle)
 }
 
 func M(haystack string, needle string) bool {
-	r := regexParser(&needle)
-	m(&haystack, r)
-	if r.Matches > 0 {
-		return true
-	} else {
-		return false
-	}
+	return defaultSession.M(haystack, needle)
 }
 
 /*
 Mr returns *RE, which is thread-safe
 */
 func Mr(haystack string, needle string) *RE {
-	return m(&haystack, regexParser(&needle))
+	return defaultSession.Mr(haystack, needle)
+}
+
+/*
+ME is Mr, but returns an error instead of panicking on an invalid needle.
+*/
+func ME(haystack string, needle string) (*RE, error) {
+	return defaultSession.ME(haystack, needle)
 }
 
 func S(haystack *string, needle string) bool {
-	r := regexParser(&needle)
-	s(haystack, r)
-	if r.Matches > 0 {
-		return true
-	} else {
-		return false
-	}
+	return defaultSession.S(haystack, needle)
 }
 
 /*
 Sr returns *RE, which is thread-safe
 */
 func Sr(haystack *string, needle string) *RE {
-	return s(haystack, regexParser(&needle))
+	return defaultSession.Sr(haystack, needle)
+}
+
+/*
+SE is Sr, but returns an error instead of panicking on an invalid needle. The haystack is untouched on error.
+*/
+func SE(haystack *string, needle string) (*RE, error) {
+	return defaultSession.SE(haystack, needle)
 }
 
 /*
 Ss returns the substituted string
 */
 func Ss(haystack string, needle string) string {
-	s(&haystack, regexParser(&needle))
-	return haystack
+	return defaultSession.Ss(haystack, needle)
+}
+
+/*
+SsE is Ss, but returns an error instead of panicking on an invalid needle.
+*/
+func SsE(haystack string, needle string) (string, error) {
+	return defaultSession.SsE(haystack, needle)
 }
 
 func m(haystack *string, r *RE) *RE {
-	R0 = r
-	if strings.Contains(*r.f, "g") {
-		captureGroups := r.regex.FindAllStringSubmatch(*haystack, -1)
-		if captureGroups == nil {
-			return r
-		}
-		r.Matches = len(captureGroups)
-		if r.captures {
-			namedCaptureGroups := r.regex.SubexpNames()
-			if r.nCaptures && len(namedCaptureGroups) > 1 {
-				r.Z = make(map[string]string, len(namedCaptureGroups))
-			}
-			r.S = make([]string, len(captureGroups)*(len(captureGroups[0])-1)+1)
-			for i, captures := range captureGroups {
-				captureGroup(r, captures, i, namedCaptureGroups)
-			}
-		}
-	} else {
-		captures := r.regex.FindStringSubmatch(*haystack)
-		if captures == nil {
-			return r
-		}
-		r.Matches = 1
-		if r.captures {
-			namedCaptureGroups := r.regex.SubexpNames()
-			if r.nCaptures && len(namedCaptureGroups) > 1 {
-				r.Z = make(map[string]string, len(namedCaptureGroups))
-			}
-			r.S = make([]string, len(captures))
-			captureGroup(r, captures, 0, namedCaptureGroups)
-		}
+	if r.mode == 't' {
+		return tr(haystack, r)
+	}
+	matches := r.findAll(*haystack, 0, r.limit())
+	if matches == nil {
+		return r
 	}
+	r.Matches = len(matches)
+	if r.captures {
+		captureGroups(r, *haystack, matches)
+	}
+	matchResults(r, *haystack, matches)
 	return r
 }
 
+/*
+s is the "must" flavour of se, it panics with a *ParseError if evaluating the replacement expression fails.
+*/
 func s(haystack *string, r *RE) *RE {
-	R0 = r
-	result := []byte{}
-	if strings.Contains(*r.f, "g") {
-		if r.captures {
-			captureGroups := r.regex.FindAllStringSubmatch(*haystack, -1)
-			if captureGroups == nil {
-				return r
-			}
-			namedCaptureGroups := r.regex.SubexpNames()
-			if r.nCaptures && len(namedCaptureGroups) > 1 {
-				r.Z = make(map[string]string, len(namedCaptureGroups))
-			}
-			r.S = make([]string, len(captureGroups)*(len(captureGroups[0])-1)+1)
-			for i, captures := range captureGroups {
-				captureGroup(r, captures, i, namedCaptureGroups)
-			}
+	r, err := se(haystack, r, nil)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+/*
+se substitutes the matches with the replacement string, with the value of the replacement expression of s///e,
+or with what the callback returns for each match. The haystack is left untouched if evaluating the expression fails.
+*/
+func se(haystack *string, r *RE, fn func(m *RE) string) (*RE, error) {
+	if r.mode == 't' {
+		return tr(haystack, r), nil
+	}
+	from := 0
+	if r.opts != nil {
+		from = r.opts.From
+	}
+	matches := r.findAll(*haystack, from, r.limit())
+	if r.opts != nil {
+		matches = r.opts.choose(matches, strings.Contains(*r.f, "g"))
+	}
+	if matches == nil {
+		if r.r {
+			r.Result = *haystack
 		}
-		// For each match of the regex in the content.
-		r.Matches = len(r.regex.FindAllStringSubmatchIndex(*haystack, -1))
-		if r.Matches > 0 {
-			*haystack = r.regex.ReplaceAllString(*haystack, *r.s)
+		return r, nil
+	}
+	r.Matches = len(matches)
+	if r.captures {
+		captureGroups(r, *haystack, matches)
+	}
+	matchResults(r, *haystack, matches)
+
+	result := []byte{}
+	last := 0
+	for i, match := range matches {
+		result = append(result, (*haystack)[last:match[0]]...)
+		var err error
+		if result, err = r.replace(result, *haystack, match, i, fn); err != nil {
+			return r, err
 		}
+		last = match[1]
+	}
+	result = append(result, (*haystack)[last:]...)
+	if r.r {
+		r.Result = string(result)
 	} else {
-		if r.captures {
-			captures := r.regex.FindStringSubmatch(*haystack)
-			if captures == nil {
-				return r
-			}
-			namedCaptureGroups := r.regex.SubexpNames()
-			if r.nCaptures && len(namedCaptureGroups) > 1 {
-				r.Z = make(map[string]string, len(namedCaptureGroups))
-			}
-			r.S = make([]string, len(captures))
-			captureGroup(r, captures, 0, namedCaptureGroups)
+		*haystack = string(result)
+	}
+	return r, nil
+}
+
+/*
+replace appends the replacement of the i:th match to dst: what the callback returns, the value of the replacement expression, or the replacement string.
+*/
+func (r *RE) replace(dst []byte, haystack string, match []int, i int, fn func(m *RE) string) ([]byte, error) {
+	start := len(dst)
+	switch {
+	case fn != nil:
+		dst = append(dst, fn(matchRE(r, haystack, match, i))...)
+	case r.expr != nil:
+		value, err := r.evalReplacement(haystack, match)
+		if err != nil {
+			return dst, err
 		}
+		dst = append(dst, value...)
+	default:
+		dst = r.expand(dst, haystack, match)
+	}
+	if r.k {
+		dst = append(dst[:start], preserveCase(haystack[match[0]:match[1]], string(dst[start:]))...)
+	}
+	return dst, nil
+}
+
+/*
+limit is how many matches to look for, all of them with the g flag, or as many as the SubstOptions need
+*/
+func (r *RE) limit() int {
+	g := strings.Contains(*r.f, "g")
+	switch {
+	case r.opts != nil:
+		return r.opts.limit(g)
+	case g:
+		return -1
+	}
+	return 1
+}
 
-		captureIdxs := r.regex.FindStringSubmatchIndex(*haystack)
-		if len(captureIdxs) > 0 {
-			r.Matches = 1
-			result = r.regex.ExpandString(nil, *r.s, *haystack, captureIdxs)
-			*haystack = (*haystack)[:captureIdxs[0]] + string(result) + (*haystack)[captureIdxs[1]:]
+/*
+captureGroups fills S, Z, ZAll and ZAllMatched from the matches
+*/
+func captureGroups(r *RE, haystack string, matches [][]int) {
+	namedCaptureGroups := r.CaptureNames()
+	if r.nCaptures {
+		r.Z = make(map[string]string, len(namedCaptureGroups))
+	}
+	r.S = make([]string, len(matches)*(len(namedCaptureGroups)-1)+1)
+	for i, match := range matches {
+		captureGroup(r, submatches(haystack, match), i, namedCaptureGroups)
+	}
+	if r.nCaptures {
+		r.ZAll = make(map[string][]string, len(namedCaptureGroups))
+		r.ZAllMatched = make(map[string][]bool, len(namedCaptureGroups))
+		for _, g := range r.groups {
+			if g.Name != "" && r.ZAll[g.Name] == nil {
+				n := 0
+				for _, other := range r.groups {
+					if other.Name == g.Name {
+						n++
+					}
+				}
+				r.ZAll[g.Name] = make([]string, 0, n*len(matches)) // the values of each name in one allocation
+				r.ZAllMatched[g.Name] = make([]bool, 0, n*len(matches))
+			}
+		}
+		for _, match := range matches {
+			for _, g := range r.groups {
+				if g.Name != "" {
+					r.ZAll[g.Name] = append(r.ZAll[g.Name], group(haystack, match, g.Index))
+					r.ZAllMatched[g.Name] = append(r.ZAllMatched[g.Name], match[2*g.Index] >= 0)
+				}
+			}
 		}
 	}
-	return r
 }
 
 func captureGroup(r *RE, captures []string, captureGroupsIteration int, namedCaptureGroups []string) {
@@ -268,27 +430,24 @@ func captureGroup(r *RE, captures []string, captureGroupsIteration int, namedCap
 	}
 }
 
-func regexParser(needle *string) *RE {
-	var r *RE
-	if UseRECache {
-		r = regexpCache.Get(needle)
-		if r != nil {
-			return r
-		}
+/*
+parseNeedle turns the needle into a compiled *RE. The variables, if any, are interpolated into the matching pattern.
+The default flags are added to the flags of the needle. With a callback, the replacement is left unparsed, the callback of Se takes its place.
+*/
+func parseNeedle(needle string, vars map[string]*Pattern, defaultFlags string, callback bool) (*RE, error) {
+	r := &RE{
+		_orig:      needle,
+		mode:       'm',
+		midComment: -1,
 	}
-
-	r = &RE{
-		_orig: *needle,
-		mode:  'm',
+	if len(r._orig) == 0 {
+		return nil, newParseError(r, ErrSyntax, ComponentMode, 0, "empty needle")
 	}
 
 	sbM := strings.Builder{}
 	sbM.Grow(len(r._orig))
 	sbS := strings.Builder{}
 	sbS.Grow(len(r._orig))
-	sbF := strings.Builder{}
-	sbF.Grow(3)
-	var sb *strings.Builder
 
 	i := 0
 	switch (r._orig)[i] {
@@ -297,100 +456,431 @@ func regexParser(needle *string) *RE {
 		i++
 	case 't':
 		r.mode = 't'
-		if (r._orig)[i+1] == 'r' {
+		if i+1 < len(r._orig) && (r._orig)[i+1] == 'r' {
 			i++
 		}
 		i++
+	case 'y':
+		r.mode = 't'
+		i++
 	case 's':
 		r.mode = 's'
 		i++
 	}
+	if i >= len(r._orig) {
+		return nil, newParseError(r, ErrMissingTerminator, ComponentMode, i, "no separator after the operation '%c'", r.mode)
+	}
 
 	r.separator = (r._orig)[i]
-	i++
+	end, err := componentParser(r, i+1, r.separator, &sbM, &r.nPos, ComponentMatch)
+	if err != nil {
+		return nil, err
+	}
+	r.nEnd = end
+	i = end + 1
+
+	if r.mode != 'm' {
+		separator := r.separator
+		if closingDelimiter(separator) != separator {
+			// A bracketed pattern has its own delimiters for the replacement, eg. s{...}{...} or s<...>/.../
+			i = delimiterSeeker(r, i)
+			if i >= len(r._orig) {
+				return nil, newParseError(r, ErrMissingTerminator, ComponentReplacement, len(r._orig), "replacement not found after the bracketed pattern")
+			}
+			separator = (r._orig)[i]
+			i++
+		}
+		end, err = componentParser(r, i, separator, &sbS, &r.sPos, ComponentReplacement)
+		if err != nil {
+			return nil, err
+		}
+		r.sEnd = end
+		i = end + 1
+	}
+	r.fOff = i
+	rn, rs, rf := sbM.String(), sbS.String(), (r._orig)[i:]
+	r.n, r.s, r.f = &rn, &rs, &rf
+
+	if err := flagValidator(r); err != nil {
+		return nil, err
+	}
+	r.r = r.mode == 's' && strings.Contains(*r.f, "r")
+	r.k = r.mode == 's' && strings.Contains(*r.f, "k")
+	r.c = r.mode == 'm' && strings.Contains(*r.f, "c")
+	r.once = r.mode == 'm' && r.separator == '?'
+	r.w = r.mode == 'm' && strings.Contains(*r.f, "w")
+	if r.mode == 't' {
+		if err := trParser(r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	if err := flagDefaults(r, defaultFlags); err != nil {
+		return nil, err
+	}
+	var interpolated [][2]int
+	if vars != nil {
+		if interpolated, err = interpolate(r, vars); err != nil {
+			return nil, err
+		}
+	}
+	flagHandler_x(r, interpolated)
+	flagHandlerGoNative(r)
+	if err := namedGroupParser(r); err != nil {
+		return nil, err
+	}
+	if err := anchorParser(r); err != nil {
+		return nil, err
+	}
+
+	regex, err := regexp.Compile(*r.n)
+	if err != nil {
+		return nil, compileError(r, err)
+	}
+	r.regex = regex
+	r.from = &searchFrom{}
+	if err := captureAnalyser(r); err != nil {
+		return nil, compileError(r, err)
+	}
+	if r.mode == 's' && callback {
+		// neither the replacement string nor the expression of s///e is used
+	} else if r.mode == 's' && r.evals > 0 {
+		if err := replacementExprParser(r); err != nil {
+			return nil, err
+		}
+	} else if r.mode == 's' {
+		if err := replacementParser(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+/*
+closingDelimiter returns the closing pair of Perl's bracketing delimiters, other delimiters close themselves.
+*/
+func closingDelimiter(open byte) byte {
+	switch open {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	case '<':
+		return '>'
+	}
+	return open
+}
 
-	var mode byte = 'm'
-	sb = &sbM
+/*
+componentParser copies the component of the needle starting at offset i into sb, until the closing delimiter.
+Bracketing delimiters nest, so m{a{2}} is the pattern a{2}. Returns the offset of the closing delimiter.
+*/
+func componentParser(r *RE, i int, open byte, sb *strings.Builder, pos *[]int, component Component) (int, error) {
+	close := closingDelimiter(open)
+	depth := 0
 	for ; i < len(r._orig); i++ {
 		switch (r._orig)[i] {
-		case r.separator:
-			switch mode {
-			case 'm':
-				if r.mode == 'm' {
-					mode = 'f'
-					sb = &sbF
-				} else {
-					mode = 's'
-					sb = &sbS
-				}
-			case 's':
-				mode = 'f'
-				sb = &sbF
-			case 'f':
-				fmt.Printf("Separator '%d' after flags-field in regex '%s'", r.separator, r._orig)
+		case close:
+			if depth == 0 {
+				return i, nil
 			}
-			continue
+			depth--
+		case open:
+			depth++ // only reachable with bracketing delimiters
+		}
+
+		switch (r._orig)[i] {
 		case '\\':
+			if i+1 >= len(r._orig) {
+				return i, newParseError(r, ErrMissingTerminator, component, i, "trailing backslash escapes the terminator '%c'", close)
+			}
 			sb.WriteByte((r._orig)[i])
+			*pos = append(*pos, i)
 			i++ // Skip the escaping backslash and the character being escaped
 			sb.WriteByte((r._orig)[i])
+			*pos = append(*pos, i)
 			continue
 		case '(':
-			if (r._orig)[i+1] == '?' && (r._orig)[i+2] == ':' { // (?:) is a non-capturing group
-
-			} else if (r._orig)[i+1] == '?' && (r._orig)[i+2] == 'P' && (r._orig)[i+3] == '<' { // (?P<) named capture groups
-				r.nCaptures = true
-				r.captures = true
-			} else {
-				r.captures = true
+			if component == ComponentMatch && r.mode != 't' && strings.HasPrefix((r._orig)[i:], "(?^") { // (?^flags:) Perl's stringified qr//, reset the flags to the defaults
+				translated, end, err := caretFlags(r, i)
+				if err != nil {
+					return i, err
+				}
+				sb.WriteString(translated)
+				for range translated {
+					*pos = append(*pos, i)
+				}
+				i = end - 1
+				continue
 			}
 		}
 		sb.WriteByte((r._orig)[i])
+		*pos = append(*pos, i)
 	}
-	if mode != 'f' && r._orig[len(r._orig)-1] != r.separator {
-		fmt.Printf("Ending terminator '%c' not found for regexp string '%s'!\n", r.separator, r._orig)
+	return i, newParseError(r, ErrMissingTerminator, component, len(r._orig), "ending terminator '%c' not found", close)
+}
+
+/*
+delimiterSeeker skips the whitespace and comments between the bracketed halves of s{...} {...}, returning the offset of the next delimiter.
+As in Perl, a '#' after whitespace starts a comment, which is only allowed with the x flag, see flagValidator.
+*/
+func delimiterSeeker(r *RE, i int) int {
+	start := i
+	for ; i < len(r._orig); i++ {
+		switch (r._orig)[i] {
+		case '\t', '\n', '\v', '\f', '\r', ' ':
+			continue
+		case '#':
+			if i == start {
+				return i // s{...}#...# uses '#' as the delimiter
+			}
+			if r.midComment < 0 {
+				r.midComment = i
+			}
+			for i < len(r._orig) && (r._orig)[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		return i
 	}
-	rn, rs, rf := sbM.String(), sbS.String(), sbF.String()
-	r.n, r.s, r.f = &rn, &rs, &rf
+	return i
+}
 
-	flagHandler_x(r)
-	flagHandlerGoNative(r)
+const supportedFlags = "gimsx"
+const unsupportedFlags = "adlnopu" // Valid Perl flags, which are not implemented
+const matchFlags = "cw"            // Flags of m// only, w is go-re specific
+const substFlags = "erk"           // Flags of s/// only, k is go-re specific
+const trFlags = "cdsr"             // Flags of tr///
 
-	r.regex = regexp.MustCompile(*r.n)
-	if UseRECache {
-		regexpCache.Put(needle, r)
+/*
+flagValidator makes sure the flags-field only contains known flags.
+Whitespace, and comments when the x flag is used, are allowed after the flags.
+*/
+func flagValidator(r *RE) error {
+	f := *r.f
+	supported, unsupported := supportedFlags, unsupportedFlags
+	if r.mode == 't' {
+		supported, unsupported = trFlags, ""
+	} else if r.mode == 's' {
+		supported += substFlags
+	} else {
+		supported += matchFlags
 	}
-	return r
+	i := 0
+	for ; i < len(f); i++ {
+		c := f[i]
+		if strings.IndexByte(supported, c) >= 0 {
+			continue
+		}
+		if r.mode == 't' && (('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')) {
+			return newParseError(r, ErrSyntax, ComponentFlags, r.fOff+i, "flag '%c' is not valid for tr///", c)
+		}
+		if strings.IndexByte(substFlags, c) >= 0 {
+			return newParseError(r, ErrSyntax, ComponentFlags, r.fOff+i, "flag '%c' is only valid for s///", c)
+		}
+		if strings.IndexByte(matchFlags, c) >= 0 {
+			return newParseError(r, ErrSyntax, ComponentFlags, r.fOff+i, "flag '%c' is only valid for m//", c)
+		}
+		if strings.IndexByte(unsupported, c) >= 0 {
+			return newParseError(r, ErrUnsupportedFeature, ComponentFlags, r.fOff+i, "flag '%c' is not supported", c)
+		}
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			return newParseError(r, ErrSyntax, ComponentFlags, r.fOff+i, "unknown flag '%c'", c)
+		}
+		break
+	}
+	flags := f[:i]
+	for j := 0; j < len(flags); j++ {
+		if flags[j] == 'e' {
+			r.evals++ // s///ee evaluates the result of the expression again
+		} else if strings.IndexByte(flags[:j], flags[j]) >= 0 {
+			r.warnings = append(r.warnings, fmt.Sprintf("flag '%c' given more than once", flags[j]))
+		}
+	}
+
+	var inComment bool
+	for ; i < len(f); i++ {
+		if inComment {
+			if f[i] == '\n' {
+				inComment = false
+			}
+			continue
+		}
+		switch f[i] {
+		case '\t', '\n', '\v', '\f', '\r', ' ':
+			continue
+		case '#':
+			if strings.IndexByte(flags, 'x') >= 0 {
+				inComment = true
+				continue
+			}
+		}
+		return newParseError(r, ErrTrailingAfterFlags, ComponentFlags, r.fOff+i, "unexpected '%c' after flags '%s'", f[i], flags)
+	}
+	*r.f = flags
+
+	if r.midComment >= 0 && strings.IndexByte(flags, 'x') < 0 {
+		return newParseError(r, ErrSyntax, ComponentReplacement, r.midComment, "comments between the pattern and the replacement need the x flag")
+	}
+	return nil
+}
+
+/*
+flagDefaults adds the default flags, eg. of a Session, to the flags of the needle.
+*/
+func flagDefaults(r *RE, defaultFlags string) error {
+	if defaultFlags == "" {
+		return nil
+	}
+	flags := *r.f
+	for i := 0; i < len(defaultFlags); i++ {
+		c := defaultFlags[i]
+		if strings.IndexByte(supportedFlags, c) < 0 {
+			return newParseError(r, ErrUnsupportedFeature, ComponentFlags, r.fOff, "default flag '%c' is not supported", c)
+		}
+		if strings.IndexByte(flags, c) < 0 {
+			flags += string(c)
+		}
+	}
+	*r.f = flags
+	return nil
+}
+
+/*
+compileError wraps the error from the Go regexp compiler, telling apart plain syntax errors from Perl features RE2 does not implement.
+The offending expression is located in the preprocessed pattern and mapped back to the needle the user wrote.
+*/
+func compileError(r *RE, err error) error {
+	pe := newParseError(r, ErrSyntax, ComponentMatch, r.nEnd, "%s", err)
+	pe.Cause = err
+	var serr *syntax.Error
+	if !errors.As(err, &serr) {
+		return pe
+	}
+	switch {
+	case serr.Code == syntax.ErrInvalidPerlOp:
+		pe.Err = ErrUnsupportedFeature
+	case serr.Code == syntax.ErrInvalidNamedCapture && (strings.HasPrefix(serr.Expr, "(?<=") || strings.HasPrefix(serr.Expr, "(?<!")):
+		pe.Err = ErrUnsupportedFeature // lookbehinds
+	case serr.Code == syntax.ErrInvalidEscape && len(serr.Expr) == 2 && '1' <= serr.Expr[1] && serr.Expr[1] <= '9':
+		pe.Err = ErrUnsupportedFeature // backreferences
+	}
+
+	var p int
+	switch serr.Code {
+	case syntax.ErrMissingParen, syntax.ErrUnexpectedParen:
+		p = parenFault(*r.n) // Expr is the whole regexp, find the parenthesis at fault
+	case syntax.ErrTrailingBackslash:
+		p = len(*r.n)
+	default:
+		p = strings.Index(*r.n, serr.Expr)
+	}
+	if p >= 0 {
+		pe.Offset = r.origOffset(p)
+	}
+	return pe
+}
+
+/*
+origOffset maps an offset in the preprocessed pattern back to the needle.
+*/
+func (r *RE) origOffset(p int) int {
+	if p < len(r.nPos) {
+		return r.nPos[p]
+	}
+	return r.nEnd
+}
+
+/*
+parenFault finds the first unexpected closing parenthesis, or the innermost unclosed opening parenthesis.
+*/
+func parenFault(regstr string) int {
+	var open []int
+	var inBracketedCharacterClass bool
+	for i := 0; i < len(regstr); i++ {
+		switch regstr[i] {
+		case '\\':
+			i++
+		case '[':
+			inBracketedCharacterClass = true
+		case ']':
+			inBracketedCharacterClass = false
+		case '(':
+			if !inBracketedCharacterClass {
+				open = append(open, i)
+			}
+		case ')':
+			if inBracketedCharacterClass {
+			} else if len(open) == 0 {
+				return i
+			} else {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	if len(open) > 0 {
+		return open[len(open)-1]
+	}
+	return -1
 }
 
 func flagHandlerGoNative(r *RE) {
+	if flags := goFlags(*r.f); flags != "" {
+		*r.n = `(?` + flags + `)` + *r.n
+		r.nPrefix = len(flags) + 3
+		prefix := make([]int, r.nPrefix, len(*r.n))
+		for i := range prefix {
+			prefix[i] = r.fOff
+		}
+		r.nPos = append(prefix, r.nPos...)
+	}
+}
+
+/*
+goFlags picks the flags the Go regexp engine implements natively
+*/
+func goFlags(flags string) string {
 	sb := strings.Builder{}
-	if strings.Contains(*r.f, "i") {
+	if strings.Contains(flags, "i") {
 		sb.WriteByte('i')
 	}
-	if strings.Contains(*r.f, "m") {
+	if strings.Contains(flags, "m") {
 		sb.WriteByte('m')
 	}
-	if strings.Contains(*r.f, "s") {
+	if strings.Contains(flags, "s") {
 		sb.WriteByte('s')
 	}
-	if sb.Len() > 0 {
-		*r.n = `(?` + sb.String() + `)` + *r.n
-	}
+	return sb.String()
 }
-func flagHandler_x(r *RE) {
+
+/*
+flagHandler_x strips the whitespace and comments of the x flag from the matching pattern, but not from the interpolated Patterns at the offsets in verbatim.
+*/
+func flagHandler_x(r *RE, verbatim [][2]int) {
 	if strings.Contains(*r.f, "x") {
-		flagHandler_x_(r, r.n)
-		flagHandler_x_(r, r.s)
+		flagHandler_x_(r, r.n, &r.nPos, verbatim) // the replacement is a string, not a pattern
 	}
 }
-func flagHandler_x_(r *RE, regstr *string) {
+func flagHandler_x_(r *RE, regstr *string, pos *[]int, verbatim [][2]int) {
 	sb := strings.Builder{}
 	sb.Grow(len(*regstr))
+	newPos := make([]int, 0, len(*pos))
 
 	var inBracketedCharacterClass bool
 	var inComment bool
 	for i := 0; i < len(*regstr); i++ {
+		if len(verbatim) > 0 && i == verbatim[0][0] {
+			if !inComment {
+				sb.WriteString((*regstr)[i:verbatim[0][1]])
+				newPos = append(newPos, (*pos)[i:verbatim[0][1]]...)
+			}
+			i = verbatim[0][1] - 1
+			verbatim = verbatim[1:]
+			continue
+		}
 		if inComment {
 			if (*regstr)[i] == '\n' {
 				inComment = false
@@ -414,8 +904,10 @@ func flagHandler_x_(r *RE, regstr *string) {
 			}
 		case '\\':
 			sb.WriteByte((*regstr)[i])
+			newPos = append(newPos, (*pos)[i])
 			i++ // Skip the escaping backslash and the character being escaped
 			sb.WriteByte((*regstr)[i])
+			newPos = append(newPos, (*pos)[i])
 			continue
 		case '#':
 			if inBracketedCharacterClass {
@@ -431,7 +923,9 @@ func flagHandler_x_(r *RE, regstr *string) {
 		}
 
 		sb.WriteByte((*regstr)[i])
+		newPos = append(newPos, (*pos)[i])
 	}
 	str := sb.String()
 	*regstr = str
+	*pos = newPos
 }
@@ -34,9 +34,7 @@ func Example() {
 	str := "This is how we party!\nFooling with our body.\n"
 	if S(&str, `s! # Do a substitution operation, start the matching pattern
 	(?:party|body) # Non-capturing substitution happening here
-	!              # close the matching pattern and begin with the substituting pattern
-	code
-	!gx            # finally some flags, x is important to allow documenting the regexp
+	!code!gx       # close the matching pattern, the replacement is a plain string, finally some flags, x is important to allow documenting the regexp
 	`) {
 		fmt.Printf("Substitution: '%s'\n", str)
 		fmt.Printf("Non-capturing group does not get captured '%d'\n", len(R0.S))
@@ -174,6 +172,38 @@ paavo: "pesus" - 2020-10-31T21:39:39.4321+0230`,
 	})
 }
 
+func TestDelimiters(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("bracketing delimiters", t, func() {
+		runMatchTest("kalle/ankka", `m{(e/a)}`, 1, []string{"", "e/a"}, nilNameCap, "")
+		runMatchTest("kallle ankka", `m{(l{2,})}`, 1, []string{"", "lll"}, nilNameCap, "Nested brackets")
+		runMatchTest("kalle ankka", `m((a)(.))g`, 2, []string{"", "a", "l", "a", "n"}, nilNameCap, "Parentheses as delimiters")
+		runMatchTest("kal<le>", `m<(<\w+>)>`, 1, []string{"", "<le>"}, nilNameCap, "")
+		runMatchTest("kalle]", `m[(\])]`, 1, []string{"", "]"}, nilNameCap, "Escaped closing delimiter")
+		runSubstTest("kalle ankka", `s{a}{u}g`, "kulle unkku", 3, nilCapture, nilNameCap)
+		runSubstTest("kalle ankka", `s<(a.)>[<$1>]`, "k<al>le ankka", 1, []string{"", "al"}, nilNameCap)
+		runSubstTest("kalle ankka", `s{ (a.) }/--/gx`, "k--le --kka", 2, []string{"", "al", "an"}, nilNameCap)
+		runSubstTest("kalle ankka", `s{a} {u}g`, "kulle unkku", 3, nilCapture, nilNameCap)
+		runSubstTest("kalle ankka", `s{a}#u#g`, "kulle unkku", 3, nilCapture, nilNameCap)
+		runSubstTest("kalle ankka", `s{
+			a     # the pattern
+		} # and the replacement
+		{u}gx`, "kulle unkku", 3, nilCapture, nilNameCap)
+		Convey("transliteration", func() {
+			str := "kalle"
+			So(Tr(&str, `tr{a-z}{A-Z}`).Matches, ShouldEqual, 5)
+			So(str, ShouldEqual, "KALLE")
+		})
+	})
+	Convey("bracketing delimiter errors", t, func() {
+		runErrorTest(`m{a{2}`, ErrMissingTerminator)
+		runErrorTest(`s{a}`, ErrMissingTerminator)
+		runErrorTest(`s{a}{b`, ErrMissingTerminator)
+		runErrorTest(`s{a} # comment
+		{b}`, ErrSyntax)
+	})
+}
+
 func runMatchTest(haystack string, needle string, matches int, captures []string, nCaptures map[string]string, description string) *RE {
 	var r *RE
 	Convey(fmt.Sprintf(`%s: "%s" match "%s" '%d' times! Captures='%d', Named captures='%d'`, description, needle, haystack, matches, len(captures)-1, len(nCaptures)), func() {
@@ -209,32 +239,32 @@ func runSubstTest(haystackA string, needle string, expected string, matches int,
 }
 
 func BenchmarkRECache_on_SimpleRE(b *testing.B) {
-	UseRECache = true
-	benchmarkRECacheComplexRE(b)
+	benchmarkRECacheComplexRE(b, NewSession())
 }
 func BenchmarkRECache_on_ComplexRE(b *testing.B) {
-	UseRECache = true
-	benchmarkRECacheComplexRE(b)
+	benchmarkRECacheComplexRE(b, NewSession())
 }
 func BenchmarkRECache_off_SimpleRE(b *testing.B) {
-	UseRECache = false
-	benchmarkRECacheComplexRE(b)
+	sess := NewSession()
+	sess.UseCache = false
+	benchmarkRECacheComplexRE(b, sess)
 }
 func BenchmarkRECache_off_ComplexRE(b *testing.B) {
-	UseRECache = false
-	benchmarkRECacheComplexRE(b)
+	sess := NewSession()
+	sess.UseCache = false
+	benchmarkRECacheComplexRE(b, sess)
 }
-func benchmarkRECacheSimpleRE(b *testing.B) {
+func benchmarkRECacheSimpleRE(b *testing.B, sess *Session) {
 	for i := 0; i < b.N; i++ {
-		M("kalle ankka", `m/kalle ankka/`)
-		if R0.Matches == 0 {
+		sess.M("kalle ankka", `m/kalle ankka/`)
+		if sess.Last.Matches == 0 {
 			b.Errorf("BenchmarkRECacheSimpleRE regexp doesnt match?")
 		}
 	}
 }
-func benchmarkRECacheComplexRE(b *testing.B) {
+func benchmarkRECacheComplexRE(b *testing.B, sess *Session) {
 	for i := 0; i < b.N; i++ {
-		M(`
+		sess.M(`
 kalle: "ankka" - 2021-12-31 23:59:59.0123+0200Z
 paavo: "pesus" - 2020-10-31T21:39:39.4321+0230`,
 			`m/
@@ -260,7 +290,7 @@ paavo: "pesus" - 2020-10-31T21:39:39.4321+0230`,
 				(?:(?P<timezone>[+-]\d{2,4})Z?)?
 				$
 			/xgms`)
-		if R0.Matches == 0 {
+		if sess.Last.Matches == 0 {
 			b.Errorf("BenchmarkRECacheComplexRE regexp doesnt match?")
 		}
 	}
@@ -0,0 +1,314 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+replacementPart is a piece of the parsed replacement string of s///
+*/
+type replacementPart struct {
+	op    byte   // one of the replace* operations
+	text  string // the literal text, or the name of the group
+	group int    // the number of the group
+}
+
+const (
+	replaceLiteral   byte = iota // text
+	replaceGroup                 // $1, ${1}, \1, $& and $0
+	replaceNamed                 // $+{name}, ${name} and $name
+	replacePrematch              // $` and ${^PREMATCH}
+	replacePostmatch             // $' and ${^POSTMATCH}
+	replaceLastParen             // $+, the highest-numbered group which participated in the match
+	replaceCase                  // \u \l \U \L \F \Q \E, the case modification in text
+)
+
+/*
+replacementParser parses the replacement string like Perl parses a double-quoted string in s///, into r.repl.
+
+	$1 ${1} \1                the capture groups
+	$& $0 ${^MATCH}           the whole match
+	$` ${^PREMATCH}           the text before the match
+	$' ${^POSTMATCH}          the text after the match
+	$+                        the highest-numbered capture group which participated in the match
+	$+{name} ${name} $name    the named capture groups
+	$$ \$ \@ \\               the characters themselves
+	\u \l                     the next character to titlecase or lowercase
+	\U \L \F \Q ... \E        uppercase, lowercase, foldcase or quotemeta until \E, see caseState
+	\n \t \x{263A} \N{U+263A} and \r \f \e \a \0 \x41 \o{101} \012 \cA, the characters they stand for
+
+With ' as the delimiter the replacement is not interpolated at all, like in Perl.
+*/
+func replacementParser(r *RE) error {
+	parts, err := replacementTemplate(r, *r.s, r.sPos, r.separator == '\'')
+	if err != nil {
+		return err
+	}
+	r.repl = parts
+	return nil
+}
+
+func replacementTemplate(r *RE, s string, pos []int, literal bool) ([]replacementPart, error) {
+	var parts []replacementPart
+	sb := strings.Builder{}
+	flush := func() {
+		if sb.Len() > 0 {
+			parts = append(parts, replacementPart{op: replaceLiteral, text: sb.String()})
+			sb.Reset()
+		}
+	}
+	add := func(part replacementPart) {
+		flush()
+		parts = append(parts, part)
+	}
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case literal:
+			if c == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '\'') {
+				i++
+			}
+			sb.WriteByte(s[i])
+			i++
+		case c == '\\' && i+1 < len(s):
+			if d := s[i+1]; '1' <= d && d <= '9' {
+				add(replacementPart{op: replaceGroup, group: int(d - '0')})
+				i += 2
+				continue
+			} else if strings.IndexByte(caseModifiers, d) >= 0 {
+				add(replacementPart{op: replaceCase, text: s[i+1 : i+2]})
+				i += 2
+				continue
+			}
+			c, end, err := replacementEscape(r, s, i, pos)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(c)
+			i = end
+		case c == '$' && i+1 < len(s):
+			part, end, err := replacementVariable(r, s, i, pos)
+			if err != nil {
+				return nil, err
+			}
+			if end == i+1 {
+				sb.WriteByte('$') // not a variable
+			} else if part.op == replaceLiteral {
+				sb.WriteString(part.text)
+			} else {
+				add(part)
+			}
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+/*
+replacementVariable parses the variable starting with the '$' at offset i, end is the offset after it, or i+1 if it is not a variable.
+*/
+func replacementVariable(r *RE, s string, i int, pos []int) (part replacementPart, end int, err error) {
+	switch c := s[i+1]; {
+	case '0' <= c && c <= '9':
+		end = i + 1
+		for end < len(s) && '0' <= s[end] && s[end] <= '9' {
+			end++
+		}
+		n, _ := strconv.Atoi(s[i+1 : end])
+		return replacementPart{op: replaceGroup, group: n}, end, nil
+	case c == '&':
+		return replacementPart{op: replaceGroup, group: 0}, i + 2, nil
+	case c == '`':
+		return replacementPart{op: replacePrematch}, i + 2, nil
+	case c == '\'':
+		return replacementPart{op: replacePostmatch}, i + 2, nil
+	case c == '$':
+		return replacementPart{op: replaceLiteral, text: "$"}, i + 2, nil
+	case c == '+':
+		if i+2 < len(s) && s[i+2] == '{' {
+			name, end := variableName(s, i+1)
+			if name == "" {
+				return part, i, newParseError(r, ErrSyntax, ComponentReplacement, pos[i], "malformed $+{name}")
+			}
+			return replacementPart{op: replaceNamed, text: name}, end, nil
+		}
+		return replacementPart{op: replaceLastParen}, i + 2, nil
+	case c == '{':
+		close := strings.IndexByte(s[i:], '}')
+		if close < 0 {
+			return part, i, newParseError(r, ErrMissingTerminator, ComponentReplacement, pos[i], "missing right brace on ${")
+		}
+		name := s[i+2 : i+close]
+		end = i + close + 1
+		switch {
+		case name == "^MATCH":
+			return replacementPart{op: replaceGroup, group: 0}, end, nil
+		case name == "^PREMATCH":
+			return replacementPart{op: replacePrematch}, end, nil
+		case name == "^POSTMATCH":
+			return replacementPart{op: replacePostmatch}, end, nil
+		}
+		if n, err := strconv.Atoi(name); err == nil && n >= 0 && name[0] != '+' {
+			return replacementPart{op: replaceGroup, group: n}, end, nil
+		}
+		if v, e := variableName(s, i); v == name && e == end {
+			return replacementPart{op: replaceNamed, text: name}, end, nil
+		}
+		return part, i, newParseError(r, ErrSyntax, ComponentReplacement, pos[i], "invalid variable '${%s}'", name)
+	default:
+		if name, end := variableName(s, i); name != "" {
+			return replacementPart{op: replaceNamed, text: name}, end, nil
+		}
+	}
+	return part, i + 1, nil
+}
+
+/*
+replacementEscape decodes the escape sequence at offset i of the replacement, returning its text and the offset after it.
+*/
+func replacementEscape(r *RE, s string, i int, pos []int) (string, int, error) {
+	switch c := s[i+1]; c {
+	case 'n':
+		return "\n", i + 2, nil
+	case 't':
+		return "\t", i + 2, nil
+	case 'r':
+		return "\r", i + 2, nil
+	case 'f':
+		return "\f", i + 2, nil
+	case 'e':
+		return "\x1b", i + 2, nil
+	case 'a':
+		return "\a", i + 2, nil
+	case 'c':
+		if i+2 >= len(s) {
+			return "", i, newParseError(r, ErrSyntax, ComponentReplacement, pos[i], "missing control character after \\c")
+		}
+		return string(rune(strings.ToUpper(s[i+2 : i+3])[0] ^ 64)), i + 3, nil
+	case 'x', 'o', 'N':
+		return replacementCodePoint(r, s, i, pos)
+	case '0':
+		end := i + 2
+		for end < len(s) && end < i+4 && '0' <= s[end] && s[end] <= '7' {
+			end++
+		}
+		n, _ := strconv.ParseUint(s[i+1:end], 8, 32)
+		return string(rune(n)), end, nil
+	default:
+		_, size := utf8.DecodeRuneInString(s[i+1:])
+		return s[i+1 : i+1+size], i + 1 + size, nil // \\, \$, \@, the escaped separator, ...
+	}
+}
+
+/*
+replacementCodePoint decodes \xHH, \x{HHHH}, \o{OOO} and \N{U+HHHH} at offset i of the replacement.
+*/
+func replacementCodePoint(r *RE, s string, i int, pos []int) (string, int, error) {
+	kind := s[i+1]
+	base, prefix := 16, ""
+	if kind == 'o' {
+		base = 8
+	} else if kind == 'N' {
+		prefix = "U+"
+	}
+
+	var digits string
+	end := i + 2
+	if end < len(s) && s[end] == '{' {
+		close := strings.IndexByte(s[end:], '}')
+		if close < 0 {
+			return "", i, newParseError(r, ErrMissingTerminator, ComponentReplacement, pos[i], "missing right brace on \\%c{}", kind)
+		}
+		digits, end = s[end+1:end+close], end+close+1
+	} else if kind == 'x' {
+		for end < len(s) && end < i+4 && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+			end++
+		}
+		digits = s[i+2 : end]
+		if digits == "" {
+			return "\x00", end, nil
+		}
+	} else {
+		return "", i, newParseError(r, ErrSyntax, ComponentReplacement, pos[i], "missing braces on \\%c{}", kind)
+	}
+
+	if !strings.HasPrefix(digits, prefix) {
+		return "", i, newParseError(r, ErrUnsupportedFeature, ComponentReplacement, pos[i], "named characters '\\N{%s}' are not supported, use \\N{U+HHHH}", digits)
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(digits[len(prefix):]), base, 32)
+	if err != nil || n > utf8.MaxRune {
+		return "", i, newParseError(r, ErrSyntax, ComponentReplacement, pos[i], "invalid character code '\\%c{%s}'", kind, digits)
+	}
+	return string(rune(n)), end, nil
+}
+
+/*
+expand appends the replacement of the match to dst.
+*/
+func (r *RE) expand(dst []byte, haystack string, match []int) []byte {
+	return expandParts(dst, r.repl, r, haystack, match)
+}
+
+/*
+expandParts appends the parsed replacement parts to dst, the groups of r taken from the match.
+*/
+func expandParts(dst []byte, parts []replacementPart, r *RE, haystack string, match []int) []byte {
+	cs := caseState{}
+	for _, part := range parts {
+		var text string
+		switch part.op {
+		case replaceLiteral:
+			text = part.text
+		case replaceGroup:
+			text = group(haystack, match, part.group)
+		case replaceNamed:
+			for _, g := range r.groups {
+				if g.Name == part.text && match[2*g.Index] >= 0 {
+					text = group(haystack, match, g.Index)
+					break
+				}
+			}
+		case replacePrematch:
+			text = haystack[:match[0]]
+		case replacePostmatch:
+			text = haystack[match[1]:]
+		case replaceLastParen:
+			for g := len(match)/2 - 1; g > 0; g-- {
+				if match[2*g] >= 0 {
+					text = group(haystack, match, g)
+					break
+				}
+			}
+		case replaceCase:
+			cs.modify(part.text[0])
+			continue
+		}
+		dst = cs.write(dst, text)
+	}
+	return dst
+}
+
+/*
+group is the text captured by the group, "" if it did not participate in the match
+*/
+func group(haystack string, match []int, group int) string {
+	if 2*group+1 < len(match) && match[2*group] >= 0 {
+		return haystack[match[2*group]:match[2*group+1]]
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReplacement(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("capture groups", t, func() {
+		runReplacementTest("kalle ankka", `s/(\w+) (\w+)/$2 $1/`, "ankka kalle")
+		runReplacementTest("kalle ankka", `s/(\w+) (\w+)/${2} \1/`, "ankka kalle")
+		runReplacementTest("kalle ankka", `s/(\w+) (\w+)/$1foo/`, "kallefoo")
+		runReplacementTest("kalle ankka", `s/(\w+) (\w+)/$3-$10/`, "-")
+		runReplacementTest("kalle ankka", `s/(?<first>\w+) (?<last>\w+)/$+{last}, ${first} $first/`, "ankka, kalle kalle")
+		runReplacementTest("kalle ankka", `s/(?<first>\w+) (?<last>\w+)/$missing/`, "")
+		runReplacementTest("Version: 1.2", `s/Version: (.*)|Revision: (.*)/$+/`, "1.2")
+		runReplacementTest("Revision: 42", `s/Version: (.*)|Revision: (.*)/$+/`, "42")
+	})
+	Convey("special variables", t, func() {
+		runReplacementTest("kalle ankka", `s/ /[$&]/`, "kalle[ ]ankka")
+		runReplacementTest("kalle ankka", `s/ /[$0]/`, "kalle[ ]ankka")
+		runReplacementTest("kalle ankka", "s/ /[$`]/", "kalle[kalle]ankka")
+		runReplacementTest("kalle ankka", `s/ /[$']/`, "kalle[ankka]ankka")
+		runReplacementTest("kalle ankka", `s/ /[${^PREMATCH}|${^MATCH}|${^POSTMATCH}]/`, "kalle[kalle| |ankka]ankka")
+		runReplacementTest("a-b-c", "s/-/($`)/g", "a(a)b(a-b)c")
+	})
+	Convey("escapes", t, func() {
+		runReplacementTest("kalle ankka", `s/ /\n\t/`, "kalle\n\tankka")
+		runReplacementTest("kalle ankka", `s/ /\x{263A}\x41\x\N{U+263A}/`, "kalle☺A\x00☺ankka")
+		runReplacementTest("kalle ankka", `s/ /\o{101}\012\0\cA/`, "kalleA\n\x00\x01ankka")
+		runReplacementTest("kalle ankka", `s/ /\$1 $$ \@ \\ \//`, `kalle$1 $ @ \ /ankka`)
+		runReplacementTest("kalle ankka", `s/ /$/`, `kalle$ankka`)
+		runReplacementTest("kalle ankka", `s/ /$-/`, `kalle$-ankka`)
+	})
+	Convey("single quotes do not interpolate", t, func() {
+		runReplacementTest("kalle ankka", `s'(\w+) (\w+)'$2 \n \' \\'`, `$2 \n ' \`)
+	})
+	Convey("the x flag does not touch the replacement", t, func() {
+		runReplacementTest("kalle ankka", `s/ (\w+) \s (\w+) /$2 # $1/x`, "ankka # kalle")
+		runReplacementTest("kalle ankka", `s{ (\w+) \s (\w+) } { $2 }x`, " ankka ")
+	})
+	Convey("Patterns with a replacement", t, func() {
+		p := MustQr(`m/(\w+) (\w+)/`).WithReplacement(`$2 $1\t`)
+		str, _ := p.Subst("kalle ankka")
+		So(str, ShouldEqual, "ankka kalle\t")
+		So(func() { MustQr(`m/a/`).WithReplacement(`\x{zz}`) }, ShouldPanic)
+	})
+	Convey("invalid replacements of Patterns", t, func() {
+		_, err := MustQr(`m/a/i`).WithReplacementE(`b\x{zz}`)
+		var perr *ParseError
+		So(errors.As(err, &perr), ShouldBeTrue)
+		So(perr.Needle, ShouldEqual, `s/a/b\x{zz}/i`)
+		So(perr.Component, ShouldEqual, ComponentReplacement)
+		So(perr.Offset, ShouldEqual, 5)
+		_, err = MustQr(`m{ a }x`).WithReplacementE(`$+{}`)
+		So(errors.As(err, &perr), ShouldBeTrue)
+		So(perr.Needle, ShouldEqual, `s{ a }{$+{}}x`)
+		So(perr.Offset, ShouldEqual, 7)
+		_, err = FromRegexp(regexp.MustCompile(`a`)).WithReplacementE(`\x{zz}`)
+		So(errors.As(err, &perr), ShouldBeTrue)
+		So(perr.Needle, ShouldEqual, `s/a/\x{zz}/`)
+		So(perr.Offset, ShouldEqual, 4)
+
+		_, err = MustQr(`s/a/b/`).WithReplacementE(`c`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		So(func() { MustQr(`tr/a/b/`).WithReplacement(`c`) }, ShouldPanic)
+		So(func() { MustQr(`s/a/1 \/ 0/e`).Subst("a") }, ShouldPanic)
+	})
+	Convey("invalid replacements", t, func() {
+		runErrorTest(`s/a/${/`, ErrMissingTerminator)
+		runErrorTest(`s/a/${1a}/`, ErrSyntax)
+		runErrorTest(`s/a/$+{}/`, ErrSyntax)
+		runErrorTest(`s/a/\x{110000}/`, ErrSyntax)
+		runErrorTest(`s/a/\N{WHITE SMILING FACE}/`, ErrUnsupportedFeature)
+		runErrorTest(`s/a/\o101/`, ErrSyntax)
+		runErrorPositionTest(`s/a/ok ${oops/`, ComponentReplacement, 7)
+	})
+}
+
+func runReplacementTest(haystack string, needle string, expected string) {
+	Convey(fmt.Sprintf(`"%s" substitutes "%s" to "%s"`, needle, haystack, expected), func() {
+		So(Ss(haystack, needle), ShouldEqual, expected)
+	})
+}
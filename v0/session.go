@@ -0,0 +1,263 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+/*
+Session owns the state Perl keeps per interpreter: the result of the last operation (like R0), the regexp cache, default flags and where to report diagnostics.
+
+	func handler(w http.ResponseWriter, req *http.Request) {
+		sess := NewSession()
+		if sess.M(req.URL.Path, `m!^/users/(?P<id>\d+)!`) {
+			id := sess.Last.Z["id"]
+		}
+	}
+
+The package-level functions M, Mr, S, ... delegate to the default session, see Default.
+A Session is not safe for concurrent use, give each goroutine or request handler its own. Sessions can share a cache, see NewSessionSharingCache.
+*/
+type Session struct {
+	UseCache    bool      // Enable/Disable transparent RE caching
+	Flags       string    // Flags applied to every needle parsed by this Session, eg. "x"
+	Diagnostics io.Writer // Non-fatal warnings and the errors the must-flavoured functions panic with are written here, nil discards them
+	Last        *RE       // The result of the latest regexp operation, like R0
+
+	cache     *reCache
+	isDefault bool            // the default session honours UseRECache and mirrors Last to R0
+	matched   map[string]bool // the m?...? needles which have matched since Reset
+	mu        sync.Mutex      // guards matched, the package-level functions share the default Session between goroutines
+}
+
+var defaultSession = &Session{
+	UseCache:  true,
+	Last:      R0,
+	cache:     regexpCache,
+	isDefault: true,
+}
+
+/*
+NewSession creates a Session with its own regexp cache.
+*/
+func NewSession() *Session {
+	return &Session{
+		UseCache: true,
+		Last:     &RE{},
+		cache:    newRECache(),
+	}
+}
+
+/*
+NewSessionSharingCache creates a Session sharing the regexp cache of the given Session, eg. one Session per request sharing the cache of the server.
+The cache is safe for concurrent use.
+*/
+func NewSessionSharingCache(other *Session) *Session {
+	sess := NewSession()
+	sess.cache = other.cache
+	return sess
+}
+
+/*
+Default returns the Session the package-level functions delegate to.
+Its Last is mirrored to R0, and its caching is controlled by UseRECache instead of the UseCache field.
+*/
+func Default() *Session {
+	return defaultSession
+}
+
+func (self *Session) useCache() bool {
+	if self.isDefault {
+		return UseRECache
+	}
+	return self.UseCache
+}
+
+func (self *Session) setLast(r *RE) {
+	self.Last = r
+	if self.isDefault {
+		R0 = r
+	}
+}
+
+func (self *Session) diagnose(format string, args ...interface{}) {
+	if self.Diagnostics != nil {
+		fmt.Fprintf(self.Diagnostics, format+"\n", args...)
+	}
+}
+
+/*
+regexParser is the "must" flavour of regexParserE, it panics with a *ParseError if the needle is invalid.
+*/
+func (self *Session) regexParser(needle *string) *RE {
+	r, err := self.regexParserE(needle)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			self.diagnose("%s", perr.Pretty())
+		}
+		panic(err)
+	}
+	return r
+}
+
+func (self *Session) regexParserE(needle *string) (*RE, error) {
+	return self.parserE(needle, false)
+}
+
+/*
+callbackParserE parses the needle of Se, leaving out the replacement the callback takes the place of.
+*/
+func (self *Session) callbackParserE(needle *string) (*RE, error) {
+	return self.parserE(needle, true)
+}
+
+func (self *Session) parserE(needle *string, callback bool) (*RE, error) {
+	key := *needle
+	if self.Flags != "" {
+		key = self.Flags + "\x00" + key
+	}
+	if callback {
+		key = "\x01" + key // parsed differently, and not a valid needle
+	}
+	var r *RE
+	if self.useCache() {
+		r = self.cache.Get(&key)
+		if r != nil {
+			return r, nil
+		}
+	}
+
+	r, err := parseNeedle(*needle, nil, self.Flags, callback)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range r.warnings {
+		self.diagnose("re: warning: %s in needle '%s'", warning, *needle)
+	}
+	if self.useCache() {
+		self.cache.Put(&key, r)
+	}
+	return r, nil
+}
+
+/*
+R is useful if you don't know the type of the regex (match/substitute) beforehand and need to dynamically do things.
+*/
+func (self *Session) R(haystack *string, needle string) bool {
+	r := self.regexParser(&needle)
+	self.setLast(r)
+	if r.mode == 's' {
+		s(haystack, r)
+	} else {
+		self.match(haystack, r, needle)
+	}
+	return r.Matches > 0
+}
+
+/*
+RrE is R, but returns the *RE and an error instead of panicking on an invalid needle.
+*/
+func (self *Session) RrE(haystack *string, needle string) (*RE, error) {
+	r, err := self.regexParserE(&needle)
+	if err != nil {
+		return nil, err
+	}
+	self.setLast(r)
+	if r.mode == 's' {
+		return se(haystack, r, nil)
+	}
+	return self.match(haystack, r, needle), nil
+}
+
+/*
+Compile parses and compiles the needle without running it against anything.
+*/
+func (self *Session) Compile(needle string) (*RE, error) {
+	return self.regexParserE(&needle)
+}
+
+func (self *Session) M(haystack string, needle string) bool {
+	return self.Mr(haystack, needle).Matches > 0
+}
+
+func (self *Session) Mr(haystack string, needle string) *RE {
+	r := self.regexParser(&needle)
+	self.setLast(r)
+	return self.match(&haystack, r, needle)
+}
+
+func (self *Session) ME(haystack string, needle string) (*RE, error) {
+	r, err := self.regexParserE(&needle)
+	if err != nil {
+		return nil, err
+	}
+	self.setLast(r)
+	return self.match(&haystack, r, needle), nil
+}
+
+func (self *Session) S(haystack *string, needle string) bool {
+	return self.Sr(haystack, needle).Matches > 0
+}
+
+func (self *Session) Sr(haystack *string, needle string) *RE {
+	r := self.regexParser(&needle)
+	self.setLast(r)
+	return s(haystack, r)
+}
+
+func (self *Session) SE(haystack *string, needle string) (*RE, error) {
+	r, err := self.regexParserE(&needle)
+	if err != nil {
+		return nil, err
+	}
+	self.setLast(r)
+	return se(haystack, r, nil)
+}
+
+func (self *Session) Ss(haystack string, needle string) string {
+	self.Sr(&haystack, needle)
+	return haystack
+}
+
+func (self *Session) SsE(haystack string, needle string) (string, error) {
+	_, err := self.SE(&haystack, needle)
+	return haystack, err
+}
+
+/*
+Qr parses the needle into a reusable Pattern, applying the Flags of the Session. Operations through the Pattern do not update Last.
+*/
+func (self *Session) Qr(needle string) (*Pattern, error) {
+	r, err := self.regexParserE(&needle)
+	if err != nil {
+		return nil, err
+	}
+	return newPattern(r), nil
+}
+
+/*
+QrWith is Qr interpolating the Patterns in vars, see the package-level QrWith.
+*/
+func (self *Session) QrWith(needle string, vars map[string]*Pattern) (*Pattern, error) {
+	if vars == nil {
+		vars = map[string]*Pattern{}
+	}
+	r, err := parseNeedle(needle, vars, self.Flags, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range r.warnings {
+		self.diagnose("re: warning: %s in needle '%s'", warning, needle)
+	}
+	return newPattern(r), nil
+}
@@ -0,0 +1,101 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleSession() {
+	sess := NewSession()
+	if sess.M("/users/42/edit", `m!^/users/(?P<id>\d+)!`) {
+		fmt.Printf("%s\n", sess.Last.Z["id"])
+	}
+	// Output: 42
+}
+
+func TestSession(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("sessions keep their own last result", t, func() {
+		a, b := NewSession(), NewSession()
+		So(a.M("kalle ankka", `m/(a.)/`), ShouldBeTrue)
+		So(b.M("minni hiiri", `m/(i.)/`), ShouldBeTrue)
+		So(a.Last.S, ShouldResemble, []string{"", "al"})
+		So(b.Last.S, ShouldResemble, []string{"", "in"})
+
+		r0 := R0
+		str := "kalle ankka"
+		So(a.S(&str, `s/a/u/g`), ShouldBeTrue)
+		So(str, ShouldEqual, "kulle unkku")
+		So(a.Last.Matches, ShouldEqual, 3)
+		So(R0, ShouldEqual, r0)
+	})
+	Convey("the default session mirrors R0", t, func() {
+		So(M("kalle ankka", `m/(a.)/g`), ShouldBeTrue)
+		So(Default().Last, ShouldEqual, R0)
+		So(R0.S, ShouldResemble, []string{"", "al", "an"})
+	})
+	Convey("concurrent sessions", t, func() {
+		shared := NewSession()
+		wg := sync.WaitGroup{}
+		failures := make(chan string, 100)
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				sess := NewSessionSharingCache(shared)
+				haystack := fmt.Sprintf("request %d", i)
+				if !sess.M(haystack, `m/(?P<n>\d+)/`) || sess.Last.Z["n"] != fmt.Sprint(i) {
+					failures <- haystack
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(failures)
+		So(len(failures), ShouldEqual, 0)
+		So(len(shared.cache.cache), ShouldEqual, 1)
+	})
+	Convey("caching can be turned off per session", t, func() {
+		sess := NewSession()
+		sess.UseCache = false
+		So(sess.M("kalle", `m/kalle/`), ShouldBeTrue)
+		So(len(sess.cache.cache), ShouldEqual, 0)
+	})
+	Convey("default flags", t, func() {
+		sess := NewSession()
+		sess.Flags = "xi"
+		So(sess.M("KALLE ankka", `m/ kalle \s ankka # spaced out /`), ShouldBeTrue)
+		r, err := sess.Compile(`m/kalle/g`)
+		So(err, ShouldBeNil)
+		So(*r.f, ShouldEqual, "gxi")
+		So(sess.Ss("kalle ankka", `s/ A /u/g`), ShouldEqual, "kulle unkku")
+
+		sess.Flags = "e"
+		_, err = sess.Compile(`m/kalle/`)
+		So(err, ShouldNotBeNil)
+	})
+	Convey("diagnostics", t, func() {
+		out := &bytes.Buffer{}
+		sess := NewSession()
+		sess.Diagnostics = out
+		So(sess.M("kalle", `m/kalle/gg`), ShouldBeTrue)
+		So(out.String(), ShouldEqual, "re: warning: flag 'g' given more than once in needle 'm/kalle/gg'\n")
+
+		out.Reset()
+		So(func() { sess.M("kalle", `m/(kalle/`) }, ShouldPanic)
+		So(strings.HasSuffix(out.String(), "m/(kalle/\n  ^\n"), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,250 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+transliteration is the parsed tr/SEARCH/REPLACE/cdsr
+*/
+type transliteration struct {
+	search  map[rune]int // rune => index in the expanded SEARCH list, the first occurrence wins
+	sorted  []rune       // the unique SEARCH runes in ascending order, to rank the complement
+	replace []rune       // the expanded REPLACE list
+
+	c bool // complement SEARCH
+	d bool // delete found characters without a counterpart in REPLACE
+	s bool // squeeze runs of the same transliterated character
+	r bool // return the new string in RE.Result, leave the haystack untouched
+}
+
+/*
+Tr transliterates the haystack in place, like Perl's $haystack =~ tr/SEARCH/REPLACE/
+*/
+func Tr(haystack *string, needle string) *RE {
+	return defaultSession.Tr(haystack, needle)
+}
+
+/*
+TrE is Tr, but returns an error instead of panicking on an invalid needle.
+*/
+func TrE(haystack *string, needle string) (*RE, error) {
+	return defaultSession.TrE(haystack, needle)
+}
+
+func (self *Session) Tr(haystack *string, needle string) *RE {
+	r := self.regexParser(&needle)
+	if r.mode != 't' {
+		panic(newParseError(r, ErrSyntax, ComponentMode, 0, "not a tr/// needle"))
+	}
+	self.setLast(r)
+	return tr(haystack, r)
+}
+
+func (self *Session) TrE(haystack *string, needle string) (*RE, error) {
+	r, err := self.regexParserE(&needle)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode != 't' {
+		return nil, newParseError(r, ErrSyntax, ComponentMode, 0, "not a tr/// needle")
+	}
+	self.setLast(r)
+	return tr(haystack, r), nil
+}
+
+func trParser(r *RE) error {
+	t := &transliteration{
+		c: strings.Contains(*r.f, "c"),
+		d: strings.Contains(*r.f, "d"),
+		s: strings.Contains(*r.f, "s"),
+		r: strings.Contains(*r.f, "r"),
+	}
+	search, err := trListParser(r, *r.n, r.nPos, ComponentMatch)
+	if err != nil {
+		return err
+	}
+	t.replace, err = trListParser(r, *r.s, r.sPos, ComponentReplacement)
+	if err != nil {
+		return err
+	}
+
+	t.search = make(map[rune]int, len(search))
+	for i, c := range search {
+		if _, ok := t.search[c]; !ok {
+			t.search[c] = i
+			t.sorted = append(t.sorted, c)
+		}
+	}
+	sort.Slice(t.sorted, func(i, j int) bool { return t.sorted[i] < t.sorted[j] })
+	r.tr = t
+	return nil
+}
+
+/*
+trListParser expands the escapes and ranges of a SEARCH or REPLACE list into runes.
+*/
+func trListParser(r *RE, list string, pos []int, component Component) ([]rune, error) {
+	runes := []rune{}
+	var rangeFrom int = -1 // index in runes of the start of a pending range
+	var afterRange bool    // a '-' right after a range is a literal
+	for i := 0; i < len(list); {
+		start := i
+		var c rune
+		if list[i] == '\\' {
+			var err error
+			c, i, err = trEscape(r, list, i, pos, component)
+			if err != nil {
+				return nil, err
+			}
+		} else if list[i] == '-' && len(runes) > 0 && i+1 < len(list) && !afterRange {
+			rangeFrom = len(runes) - 1
+			i++
+			continue
+		} else {
+			var size int
+			c, size = utf8.DecodeRuneInString(list[i:])
+			i += size
+		}
+
+		if rangeFrom < 0 {
+			runes = append(runes, c)
+			afterRange = false
+			continue
+		}
+		from := runes[rangeFrom]
+		if c < from {
+			return nil, newParseError(r, ErrSyntax, component, pos[start], "invalid range '%c-%c' in transliteration operator", from, c)
+		}
+		for from++; from <= c; from++ {
+			runes = append(runes, from)
+		}
+		rangeFrom, afterRange = -1, true
+	}
+	return runes, nil
+}
+
+/*
+trEscape decodes the escape sequence at list[i], returning the rune and the offset after the sequence.
+*/
+func trEscape(r *RE, list string, i int, pos []int, component Component) (rune, int, error) {
+	switch c := list[i+1]; c {
+	case 'n':
+		return '\n', i + 2, nil
+	case 't':
+		return '\t', i + 2, nil
+	case 'r':
+		return '\r', i + 2, nil
+	case 'f':
+		return '\f', i + 2, nil
+	case 'e':
+		return '\x1b', i + 2, nil
+	case 'a':
+		return '\a', i + 2, nil
+	case 'x':
+		var digits string
+		end := i + 2
+		if end < len(list) && list[end] == '{' {
+			close := strings.IndexByte(list[end:], '}')
+			if close < 0 {
+				return 0, i, newParseError(r, ErrSyntax, component, pos[i], "missing right brace on \\x{}")
+			}
+			digits, end = list[end+1:end+close], end+close+1
+		} else {
+			for end < len(list) && end < i+4 && strings.IndexByte("0123456789abcdefABCDEF", list[end]) >= 0 {
+				end++
+			}
+			digits = list[i+2 : end]
+		}
+		if digits == "" {
+			return 0, end, nil
+		}
+		n, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || n > utf8.MaxRune {
+			return 0, i, newParseError(r, ErrSyntax, component, pos[i], "invalid hexadecimal escape '\\x%s'", digits)
+		}
+		return rune(n), end, nil
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		end := i + 1
+		for end < len(list) && end < i+4 && '0' <= list[end] && list[end] <= '7' {
+			end++
+		}
+		n, _ := strconv.ParseUint(list[i+1:end], 8, 32)
+		return rune(n), end, nil
+	default:
+		c, size := utf8.DecodeRuneInString(list[i+1:])
+		return c, i + 1 + size, nil // \\, \-, the escaped separator, ...
+	}
+}
+
+/*
+lookup finds the index of the rune in the REPLACE list, found tells if the rune is in the (complemented) SEARCH list at all
+*/
+func (t *transliteration) lookup(c rune) (index int, found bool) {
+	if !t.c {
+		index, found = t.search[c]
+		return index, found
+	}
+	if _, ok := t.search[c]; ok {
+		return 0, false
+	}
+	// The complement is ordered by code point, so the index is the code point minus the SEARCH runes below it
+	return int(c) - sort.Search(len(t.sorted), func(i int) bool { return t.sorted[i] >= c }), true
+}
+
+func tr(haystack *string, r *RE) *RE {
+	t := r.tr
+	sb := strings.Builder{}
+	sb.Grow(len(*haystack))
+
+	var last rune
+	var squeezing bool
+	for i := 0; i < len(*haystack); {
+		c, size := utf8.DecodeRuneInString((*haystack)[i:])
+		index, found := t.lookup(c)
+		if c == utf8.RuneError && size == 1 {
+			found = false
+		}
+		if !found {
+			sb.WriteString((*haystack)[i : i+size]) // keep invalid UTF-8 untouched
+			i += size
+			squeezing = false
+			continue
+		}
+		i += size
+		r.Matches++
+
+		out := c
+		if index < len(t.replace) {
+			out = t.replace[index]
+		} else if t.d {
+			continue
+		} else if len(t.replace) > 0 {
+			out = t.replace[len(t.replace)-1]
+		}
+		if t.s && squeezing && out == last {
+			continue
+		}
+		sb.WriteRune(out)
+		last, squeezing = out, true
+	}
+
+	if t.r {
+		r.Result = sb.String()
+	} else if r.Matches > 0 {
+		*haystack = sb.String()
+	}
+	return r
+}
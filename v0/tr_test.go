@@ -0,0 +1,91 @@
+/*
+This file is part of go-re
+
+Copyright © 2021 Technology Innovation Institute, United Arab Emirates
+
+Licensed under the Artistic License, Version 2.0 (the "License");
+    https://www.perlfoundation.org/artistic-license-20
+*/
+
+package re
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ExampleTr() {
+	str := "Hyppytyynytyydytys"
+	r := Tr(&str, `tr/a-z/A-Z/`)
+	fmt.Printf("%s %d\n", str, r.Matches)
+
+	str = "kalle    ankka"
+	Tr(&str, `y/a-zA-Z/ /cs`)
+	fmt.Printf("%s\n", str)
+	// Output: HYPPYTYYNYTYYDYTYS 17
+	// kalle ankka
+}
+
+func TestTr(t *testing.T) {
+	SetDefaultFailureMode(FailureContinues)
+	Convey("transliteration", t, func() {
+		runTrTest("kalle ankka", `tr/a-z/A-Z/`, "KALLE ANKKA", 10)
+		runTrTest("kalle ankka", `y/a-z/A-Z/`, "KALLE ANKKA", 10)
+		runTrTest("kalle ankka", `t/ak/AK/`, "KAlle AnKKA", 6)
+		runTrTest("kalle ankka", `tr/a//`, "kalle ankka", 3)
+		runTrTest("kalle ankka", `tr/a-e/x/`, "kxllx xnkkx", 4)
+		runTrTest("kalle ankka", `tr/ale/A/d`, "kA AnkkA", 6)
+		runTrTest("kalle ankka", `tr/a-zA-Z//cd`, "kalleankka", 1)
+		runTrTest("kalle   ankka", `tr/a-zA-Z/_/c`, "kalle___ankka", 3)
+		runTrTest("kalle   ankka", `tr/a-zA-Z/_/cs`, "kalle_ankka", 3)
+		runTrTest("kallle ankkka", `tr/a-z//s`, "kale anka", 12)
+		runTrTest("aaa", `tr/a/a/cs`, "aaa", 0)
+		runTrTest("a-b/c", `tr/\-\/a/_|A/`, "A_b|c", 3)
+		runTrTest("a-b", `tr/a-/A_/`, "A_b", 2)
+		runTrTest("a-c", `tr/a-c-/x/`, "xxx", 3)
+		runTrTest("tab\there\n", `tr/\t\n/ /`, "tab here ", 2)
+		runTrTest("hymy", `tr/\x79\x{6D}/\x{263A}\101/`, "h☺A☺", 3)
+		runTrTest("ääkkönen", `tr/äöå/aoa/`, "aakkonen", 3)
+		runTrTest("smile ☺", `tr/☺-☻/:/`, "smile :", 1)
+		runTrTest("\xffkalle", `tr/a-z/A-Z/`, "\xffKALLE", 5)
+	})
+	Convey("non-destructive transliteration", t, func() {
+		str := "kalle ankka"
+		r := Tr(&str, `tr/a-z/A-Z/r`)
+		So(str, ShouldEqual, "kalle ankka")
+		So(r.Result, ShouldEqual, "KALLE ANKKA")
+		So(r.Matches, ShouldEqual, 10)
+	})
+	Convey("counting with M and R", t, func() {
+		So(Mr("kalle ankka", `tr/a//`).Matches, ShouldEqual, 3)
+		So(M("kalle ankka", `tr/x//`), ShouldBeFalse)
+		str := "kalle"
+		So(R(&str, `tr/a-z/A-Z/`), ShouldBeTrue)
+		So(str, ShouldEqual, "KALLE")
+		So(R0.Matches, ShouldEqual, 5)
+	})
+	Convey("errors", t, func() {
+		_, err := Compile(`tr/z-a/x/`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		So(err.(*ParseError).Offset, ShouldEqual, 5)
+		_, err = Compile(`tr/a-z/A-Z/g`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		_, err = Compile(`tr/a-z/\x{110000}/`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+		str := "kalle"
+		_, err = TrE(&str, `s/a/b/`)
+		So(errors.Is(err, ErrSyntax), ShouldBeTrue)
+	})
+}
+
+func runTrTest(haystack string, needle string, expected string, matches int) {
+	Convey(fmt.Sprintf(`"%s" transliterated "%s" '%d' times`, needle, haystack, matches), func() {
+		r, err := TrE(&haystack, needle)
+		So(err, ShouldBeNil)
+		So(haystack, ShouldEqual, expected)
+		So(r.Matches, ShouldEqual, matches)
+	})
+}